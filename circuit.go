@@ -0,0 +1,420 @@
+package eurekache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCircuitFailures    = 5
+	defaultCircuitMinRequests = 20
+	defaultCircuitWindow      = 10 * time.Second
+	defaultCircuitCoolDown    = 5 * time.Second
+	defaultCircuitProbes      = 1
+)
+
+// ErrCircuitOpen is returned when the circuit breaker skips the cache source
+var ErrCircuitOpen = errors.New("eurekache: circuit breaker is open")
+
+// CircuitState is a state of circuit breaker
+type CircuitState int
+
+// circuit states
+const (
+	// CircuitClosed passes all of operations into the cache source
+	CircuitClosed CircuitState = iota
+	// CircuitOpen skips all of operations until the cool-down ends
+	CircuitOpen
+	// CircuitHalfOpen passes limited number of operations to probe the cache source
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerCache is a cache source wrapping another cache source,
+// and skips the source while it's unhealthy.
+// The circuit is opened when failures continue or the error rate exceeds the threshold,
+// and it's half-opened after the cool-down to probe the source.
+// Failures are detected from errors of Lookup, LookupContext, LookupItem and writes,
+// and cache miss, ErrDecode, ErrTypeMismatch and cancellation by the caller are not counted as failures.
+// GetInterface and GetGobBytes don't return errors, so only their hits are counted as successes.
+type CircuitBreakerCache struct {
+	cache Cache
+
+	mu            sync.Mutex
+	maxFailures   int
+	errorRate     float64
+	minRequests   int
+	window        time.Duration
+	coolDown      time.Duration
+	probes        int
+	onStateChange func(string, CircuitState, CircuitState)
+
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	consecutive int
+	openedAt    time.Time
+	inflight    int
+	successes   int
+}
+
+// NewCircuitBreakerCache returns initialized CircuitBreakerCache
+func NewCircuitBreakerCache(cache Cache) *CircuitBreakerCache {
+	return &CircuitBreakerCache{
+		cache:       cache,
+		maxFailures: defaultCircuitFailures,
+		minRequests: defaultCircuitMinRequests,
+		window:      defaultCircuitWindow,
+		coolDown:    defaultCircuitCoolDown,
+		probes:      defaultCircuitProbes,
+		windowStart: time.Now(),
+	}
+}
+
+// SetConsecutiveFailures sets the number of consecutive failures to open the circuit.
+// 0 disables the threshold.
+func (c *CircuitBreakerCache) SetConsecutiveFailures(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxFailures = n
+}
+
+// SetErrorRate sets the error rate to open the circuit in the window.
+// the rate is checked when the number of operations in the window reaches minRequests.
+// 0 disables the threshold.
+func (c *CircuitBreakerCache) SetErrorRate(rate float64, minRequests int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errorRate = rate
+	c.minRequests = minRequests
+}
+
+// SetWindow sets the time window to calculate the error rate
+func (c *CircuitBreakerCache) SetWindow(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.window = d
+}
+
+// SetCoolDown sets the time to keep the circuit open
+func (c *CircuitBreakerCache) SetCoolDown(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.coolDown = d
+}
+
+// SetHalfOpenProbes sets the number of operations passed on half-open state.
+// the circuit is closed when all of them succeeded.
+func (c *CircuitBreakerCache) SetHalfOpenProbes(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.probes = n
+}
+
+// SetStateChangeHandler sets handler called when the state of the circuit is changed
+func (c *CircuitBreakerCache) SetStateChangeHandler(fn func(name string, from, to CircuitState)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onStateChange = fn
+}
+
+// State returns current state of the circuit.
+func (c *CircuitBreakerCache) State() CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= c.coolDown {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// Name returns the name of the cache source.
+func (c *CircuitBreakerCache) Name() string {
+	return sourceName(c.cache)
+}
+
+// Ping checks the cache source regardless of the state of the circuit.
+func (c *CircuitBreakerCache) Ping(ctx context.Context) error {
+	if p, ok := c.cache.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// Get searches cache from the cache source when the circuit is not open.
+func (c *CircuitBreakerCache) Get(key string, data interface{}) bool {
+	ok, _ := c.LookupContext(context.Background(), key, data)
+	return ok
+}
+
+// Lookup searches cache from the cache source when the circuit is not open.
+func (c *CircuitBreakerCache) Lookup(key string, data interface{}) (bool, error) {
+	return c.LookupContext(context.Background(), key, data)
+}
+
+// LookupContext searches cache from the cache source when the circuit is not open.
+func (c *CircuitBreakerCache) LookupContext(ctx context.Context, key string, data interface{}) (bool, error) {
+	if !c.allow() {
+		return false, ErrCircuitOpen
+	}
+
+	ok, err := lookupContext(ctx, c.cache, key, data)
+	c.recordContext(ctx, err)
+	return ok, err
+}
+
+// LookupItem searches cache from the cache source when the circuit is not open.
+func (c *CircuitBreakerCache) LookupItem(ctx context.Context, key string, data interface{}) (*Item, bool, error) {
+	if !c.allow() {
+		return nil, false, ErrCircuitOpen
+	}
+
+	item, ok, err := lookupItem(ctx, c.cache, key, data)
+	c.recordContext(ctx, err)
+	return item, ok, err
+}
+
+// GetInterface searches cache from the cache source when the circuit is not open.
+func (c *CircuitBreakerCache) GetInterface(key string) (interface{}, bool) {
+	if !c.allow() {
+		return nil, false
+	}
+
+	v, ok := c.cache.GetInterface(key)
+	c.recordHit(ok)
+	return v, ok
+}
+
+// GetGobBytes searches cache from the cache source when the circuit is not open.
+func (c *CircuitBreakerCache) GetGobBytes(key string) ([]byte, bool) {
+	if !c.allow() {
+		return nil, false
+	}
+
+	b, ok := c.cache.GetGobBytes(key)
+	c.recordHit(ok)
+	return b, ok
+}
+
+// Set sets data into the cache source when the circuit is not open.
+func (c *CircuitBreakerCache) Set(key string, data interface{}) error {
+	return c.do(func() error {
+		return c.cache.Set(key, data)
+	})
+}
+
+// SetExpire sets data with TTL into the cache source when the circuit is not open.
+func (c *CircuitBreakerCache) SetExpire(key string, data interface{}, ttl int64) error {
+	return c.do(func() error {
+		return c.cache.SetExpire(key, data, ttl)
+	})
+}
+
+// SetWithTags sets data with TTL and tags into the cache source when the circuit is not open.
+func (c *CircuitBreakerCache) SetWithTags(key string, data interface{}, ttl int64, tags ...string) error {
+	return c.do(func() error {
+		return writeEntry(c.cache, WriteEntry{
+			Key:   key,
+			Value: data,
+			TTL:   ttl,
+			Tags:  tags,
+		})
+	})
+}
+
+// InvalidateTag invalidates the tag on the cache source when the circuit is not open.
+func (c *CircuitBreakerCache) InvalidateTag(tag string) error {
+	tc, ok := c.cache.(TagCache)
+	if !ok {
+		return nil
+	}
+	return c.do(func() error {
+		return tc.InvalidateTag(tag)
+	})
+}
+
+// Clear deletes all of cached data from the cache source when the circuit is not open.
+func (c *CircuitBreakerCache) Clear() error {
+	return c.do(c.cache.Clear)
+}
+
+// do runs fn when the circuit is not open and records the result
+func (c *CircuitBreakerCache) do(fn func() error) error {
+	if !c.allow() {
+		return ErrCircuitOpen
+	}
+
+	err := fn()
+	c.record(err)
+	return err
+}
+
+// allow checks the operation can be passed into the cache source or not
+func (c *CircuitBreakerCache) allow() bool {
+	c.mu.Lock()
+	var notify func()
+	defer func() {
+		c.mu.Unlock()
+		if notify != nil {
+			notify()
+		}
+	}()
+
+	switch c.state {
+	case CircuitClosed:
+		return true
+	case CircuitOpen:
+		if time.Since(c.openedAt) < c.coolDown {
+			return false
+		}
+		notify = c.setState(CircuitHalfOpen)
+	}
+
+	// half-open
+	if c.inflight >= c.probes {
+		return false
+	}
+	c.inflight++
+	return true
+}
+
+// release finishes the operation without the result
+func (c *CircuitBreakerCache) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == CircuitHalfOpen && c.inflight > 0 {
+		c.inflight--
+	}
+}
+
+// recordContext records the result of the operation with ctx.
+// The operation cancelled by the caller (e.g. the rest of ReadParallel) is not recorded.
+func (c *CircuitBreakerCache) recordContext(ctx context.Context, err error) {
+	if ctx.Err() != nil && errors.Is(err, context.Canceled) {
+		c.release()
+		return
+	}
+	c.record(err)
+}
+
+// recordHit records the hit as a success, and the miss is not recorded
+// because it's not distinguishable from a failure.
+func (c *CircuitBreakerCache) recordHit(ok bool) {
+	if !ok {
+		c.release()
+		return
+	}
+	c.record(nil)
+}
+
+// record updates the state by the result of the operation
+func (c *CircuitBreakerCache) record(err error) {
+	failed := isFailure(err)
+
+	c.mu.Lock()
+	var notify func()
+	defer func() {
+		c.mu.Unlock()
+		if notify != nil {
+			notify()
+		}
+	}()
+
+	switch c.state {
+	case CircuitHalfOpen:
+		if c.inflight > 0 {
+			c.inflight--
+		}
+		if failed {
+			notify = c.setState(CircuitOpen)
+			return
+		}
+		c.successes++
+		if c.successes >= c.probes {
+			notify = c.setState(CircuitClosed)
+		}
+	case CircuitClosed:
+		now := time.Now()
+		if now.Sub(c.windowStart) >= c.window {
+			c.windowStart = now
+			c.requests = 0
+			c.failures = 0
+		}
+
+		c.requests++
+		if !failed {
+			c.consecutive = 0
+			return
+		}
+		c.failures++
+		c.consecutive++
+		if c.shouldOpen() {
+			notify = c.setState(CircuitOpen)
+		}
+	}
+}
+
+// shouldOpen checks the failures exceed the thresholds
+func (c *CircuitBreakerCache) shouldOpen() bool {
+	if c.maxFailures > 0 && c.consecutive >= c.maxFailures {
+		return true
+	}
+	if c.errorRate > 0 && c.requests >= c.minRequests {
+		return float64(c.failures)/float64(c.requests) >= c.errorRate
+	}
+	return false
+}
+
+// setState changes the state and returns the function to call the handler.
+// the handler is called after unlocking.
+func (c *CircuitBreakerCache) setState(state CircuitState) func() {
+	from := c.state
+	c.state = state
+	c.inflight = 0
+	c.successes = 0
+
+	switch state {
+	case CircuitOpen:
+		c.openedAt = time.Now()
+	case CircuitClosed:
+		c.windowStart = time.Now()
+		c.requests = 0
+		c.failures = 0
+		c.consecutive = 0
+	}
+
+	fn := c.onStateChange
+	if fn == nil {
+		return nil
+	}
+	name := c.Name()
+	return func() {
+		fn(name, from, state)
+	}
+}
+
+// isFailure checks the error is caused by unhealthy cache source or not
+func isFailure(err error) bool {
+	switch {
+	case err == nil,
+		errors.Is(err, ErrDecode),
+		errors.Is(err, ErrTypeMismatch):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,277 @@
+package eurekache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitStateString(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("closed", CircuitClosed.String())
+	assert.Equal("open", CircuitOpen.String())
+	assert.Equal("half-open", CircuitHalfOpen.String())
+	assert.Equal("unknown", CircuitState(99).String())
+}
+
+func TestCircuitBreakerCacheConsecutiveFailures(t *testing.T) {
+	assert := assert.New(t)
+
+	src := &dummyFailCache{err: ErrConnection}
+	c := NewCircuitBreakerCache(src)
+	c.SetConsecutiveFailures(3)
+	c.SetCoolDown(time.Hour)
+
+	var transitions []string
+	c.SetStateChangeHandler(func(name string, from, to CircuitState) {
+		transitions = append(transitions, name+":"+from.String()+"->"+to.String())
+	})
+
+	var v string
+	for i := 0; i < 3; i++ {
+		ok, err := c.Lookup("key", &v)
+		assert.False(ok)
+		assert.Equal(ErrConnection, err)
+	}
+	assert.Equal(CircuitOpen, c.State())
+	assert.Equal([]string{"*eurekache.dummyFailCache:closed->open"}, transitions)
+
+	// skip the cache source
+	ok, err := c.Lookup("key", &v)
+	assert.False(ok)
+	assert.Equal(ErrCircuitOpen, err)
+	assert.Equal(ErrCircuitOpen, c.Set("key", "value"))
+	_, ok = c.GetInterface("key")
+	assert.False(ok)
+	assert.Equal(3, src.count())
+}
+
+func TestCircuitBreakerCacheIgnoredErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	src := &dummyFailCache{}
+	c := NewCircuitBreakerCache(src)
+	c.SetConsecutiveFailures(2)
+
+	var v string
+	src.setError(ErrDecode)
+	c.Lookup("key", &v)
+	src.setError(WrapError(ErrTypeMismatch, errors.New("mismatch")))
+	c.Lookup("key", &v)
+	src.setError(nil)
+	c.Lookup("key", &v)
+	assert.Equal(CircuitClosed, c.State())
+
+	// success resets consecutive failures
+	src.setError(ErrTimeout)
+	c.Lookup("key", &v)
+	src.setError(nil)
+	c.Lookup("key", &v)
+	src.setError(ErrTimeout)
+	c.Lookup("key", &v)
+	assert.Equal(CircuitClosed, c.State())
+}
+
+func TestCircuitBreakerCacheErrorRate(t *testing.T) {
+	assert := assert.New(t)
+
+	src := &dummyFailCache{}
+	c := NewCircuitBreakerCache(src)
+	c.SetConsecutiveFailures(0)
+	c.SetErrorRate(0.5, 4)
+
+	for _, err := range []error{ErrConnection, nil, ErrConnection} {
+		src.setError(err)
+		c.Set("key", "value")
+	}
+	assert.Equal(CircuitClosed, c.State(), "under minimum requests")
+
+	src.setError(ErrConnection)
+	c.Set("key", "value")
+	assert.Equal(CircuitOpen, c.State())
+
+	// counts are reset by the window
+	c = NewCircuitBreakerCache(src)
+	c.SetConsecutiveFailures(0)
+	c.SetErrorRate(0.5, 2)
+	c.SetWindow(20 * time.Millisecond)
+	c.Set("key", "value")
+	time.Sleep(30 * time.Millisecond)
+	src.setError(nil)
+	c.Set("key", "value")
+	assert.Equal(CircuitClosed, c.State())
+}
+
+func TestCircuitBreakerCacheHalfOpen(t *testing.T) {
+	assert := assert.New(t)
+
+	src := &dummyFailCache{err: ErrConnection}
+	c := NewCircuitBreakerCache(src)
+	c.SetConsecutiveFailures(1)
+	c.SetCoolDown(20 * time.Millisecond)
+
+	var mu sync.Mutex
+	var transitions []CircuitState
+	c.SetStateChangeHandler(func(name string, from, to CircuitState) {
+		mu.Lock()
+		defer mu.Unlock()
+		transitions = append(transitions, to)
+	})
+
+	c.Set("key", "value")
+	assert.Equal(CircuitOpen, c.State())
+
+	// probe fails and the circuit is opened again
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(CircuitHalfOpen, c.State())
+	assert.Equal(ErrConnection, c.Set("key", "value"))
+	assert.Equal(CircuitOpen, c.State())
+
+	// only one probe is passed on half-open
+	time.Sleep(30 * time.Millisecond)
+	src.setError(nil)
+	src.setSleep(20 * time.Millisecond)
+	done := make(chan error)
+	go func() {
+		done <- c.Set("key", "value")
+	}()
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(ErrCircuitOpen, c.Set("key", "value"))
+
+	// probe succeeds and the circuit is closed
+	assert.NoError(<-done)
+	assert.Equal(CircuitClosed, c.State())
+	assert.Equal([]CircuitState{CircuitOpen, CircuitHalfOpen, CircuitOpen, CircuitHalfOpen, CircuitClosed}, transitions)
+}
+
+func TestCircuitBreakerCacheWithEurekache(t *testing.T) {
+	assert := assert.New(t)
+
+	broken := &dummyFailCache{err: ErrConnection}
+	c := NewCircuitBreakerCache(broken)
+	c.SetConsecutiveFailures(1)
+	c.SetCoolDown(time.Hour)
+
+	e := New()
+	e.SetCacheSources([]Cache{c, &dummyHedgeCache{value: "value"}})
+
+	var v string
+	ok, err := e.LookupContext(context.Background(), "key", &v)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("value", v)
+
+	ok, err = e.LookupContext(context.Background(), "key", &v)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal(1, broken.count())
+}
+
+func TestCircuitBreakerCacheReadParallel(t *testing.T) {
+	assert := assert.New(t)
+
+	// healthy but slower source is cancelled by the hit of higher-priority source
+	src := &dummyHedgeCache{sleep: 50 * time.Millisecond, value: "value"}
+	c := NewCircuitBreakerCache(src)
+	c.SetConsecutiveFailures(3)
+
+	e := New()
+	e.SetCacheSources([]Cache{&dummyHedgeCache{value: "memory"}, c})
+	e.SetReadStrategy(ReadParallel)
+
+	var v string
+	for i := 0; i < 10; i++ {
+		assert.True(e.Get("key", &v))
+		assert.Equal("memory", v)
+	}
+	assert.Eventually(func() bool { return src.cancelCount() == 10 }, time.Second, time.Millisecond)
+	assert.Equal(CircuitClosed, c.State())
+
+	// cancellation is counted as failure when ctx is not done
+	ctx := context.Background()
+	failing := NewCircuitBreakerCache(&dummyFailCache{err: WrapError(ErrConnection, context.Canceled)})
+	failing.SetConsecutiveFailures(1)
+	failing.LookupContext(ctx, "key", &v)
+	assert.Equal(CircuitOpen, failing.State())
+}
+
+func TestCircuitBreakerCacheGetInterface(t *testing.T) {
+	assert := assert.New(t)
+
+	src := &dummyHedgeCache{err: ErrConnection}
+	c := NewCircuitBreakerCache(src)
+	c.SetConsecutiveFailures(1)
+	c.SetCoolDown(10 * time.Millisecond)
+
+	var v string
+	c.Lookup("key", &v)
+	assert.Equal(CircuitOpen, c.State())
+	src.err = nil
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(CircuitHalfOpen, c.State())
+
+	// miss is not regarded as success
+	_, ok := c.GetInterface("key")
+	assert.False(ok)
+	assert.Equal(CircuitHalfOpen, c.State())
+
+	// hit closes the circuit
+	src.value = "value"
+	_, ok = c.GetInterface("key")
+	assert.True(ok)
+	assert.Equal(CircuitClosed, c.State())
+	assert.True(c.Get("key", &v))
+}
+
+type dummyFailCache struct {
+	dummyCache
+	mu     sync.Mutex
+	err    error
+	sleep  time.Duration
+	called int
+}
+
+func (d *dummyFailCache) Lookup(k string, v interface{}) (bool, error) {
+	return false, d.call()
+}
+
+func (d *dummyFailCache) SetExpire(k string, v interface{}, ttl int64) error {
+	return d.call()
+}
+
+func (d *dummyFailCache) Set(k string, v interface{}) error {
+	return d.call()
+}
+
+func (d *dummyFailCache) call() error {
+	d.mu.Lock()
+	d.called++
+	err, sleep := d.err, d.sleep
+	d.mu.Unlock()
+
+	time.Sleep(sleep)
+	return err
+}
+
+func (d *dummyFailCache) setError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.err = err
+}
+
+func (d *dummyFailCache) setSleep(sleep time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sleep = sleep
+}
+
+func (d *dummyFailCache) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.called
+}
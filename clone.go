@@ -0,0 +1,39 @@
+package eurekache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+)
+
+// Cloner is interface for value returning its deep copy
+type Cloner interface {
+	Clone() interface{}
+}
+
+// Clone returns deep copy of the value.
+// Cloner is used when the value implements it, otherwise the value is copied via encoding/gob,
+// so unexported fields are not copied.
+func Clone(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if c, ok := v.(Cloner); ok {
+		return c.Clone(), nil
+	}
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	result := reflect.New(reflect.TypeOf(v))
+	dec := gob.NewDecoder(&buf)
+	err = dec.Decode(result.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return result.Elem().Interface(), nil
+}
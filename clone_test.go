@@ -0,0 +1,54 @@
+package eurekache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cloneTestValue struct {
+	Values []string
+}
+
+type cloneTestCloner struct {
+	cloned bool
+}
+
+func (c *cloneTestCloner) Clone() interface{} {
+	return &cloneTestCloner{cloned: true}
+}
+
+func TestClone(t *testing.T) {
+	assert := assert.New(t)
+
+	// nil
+	v, err := Clone(nil)
+	assert.NoError(err)
+	assert.Nil(v)
+
+	// map
+	m := map[string]int{"a": 1}
+	v, err = Clone(m)
+	assert.NoError(err)
+	assert.Equal(m, v)
+	v.(map[string]int)["a"] = 2
+	assert.Equal(1, m["a"])
+
+	// pointer
+	p := &cloneTestValue{Values: []string{"a", "b"}}
+	v, err = Clone(p)
+	assert.NoError(err)
+	assert.Equal(p, v)
+	v.(*cloneTestValue).Values[0] = "c"
+	assert.Equal("a", p.Values[0])
+
+	// Cloner
+	c := &cloneTestCloner{}
+	v, err = Clone(c)
+	assert.NoError(err)
+	assert.True(v.(*cloneTestCloner).cloned)
+
+	// unsupported type
+	_, err = Clone(func() {})
+	assert.Error(err)
+}
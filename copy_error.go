@@ -0,0 +1,32 @@
+package eurekache
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// CopyError is returned when CopyValueWithError refuses the copy.
+type CopyError struct {
+	Dst    reflect.Type
+	Src    reflect.Type
+	Reason string
+}
+
+// newCopyError returns *CopyError for given values
+func newCopyError(dst, src interface{}, reason string) *CopyError {
+	return &CopyError{
+		Dst:    reflect.TypeOf(dst),
+		Src:    reflect.TypeOf(src),
+		Reason: reason,
+	}
+}
+
+// Error returns error message.
+func (e *CopyError) Error() string {
+	return fmt.Sprintf("eurekache: cannot copy %v into %v: %s", e.Src, e.Dst, e.Reason)
+}
+
+// Is checks if target is ErrTypeMismatch or not.
+func (e *CopyError) Is(target error) bool {
+	return target == ErrTypeMismatch
+}
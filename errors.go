@@ -0,0 +1,64 @@
+package eurekache
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// errors returned from cache operations, use errors.Is to check the kind of error.
+var (
+	// ErrTimeout is returned when the operation is timed out
+	ErrTimeout = errors.New("eurekache: operation timed out")
+	// ErrConnection is returned when cache source cannot be connected
+	ErrConnection = errors.New("eurekache: failed to connect to cache source")
+	// ErrDecode is returned when cached data cannot be encoded or decoded
+	ErrDecode = errors.New("eurekache: failed to decode cached data")
+	// ErrTypeMismatch is returned when cached value cannot be assigned to the destination
+	ErrTypeMismatch = errors.New("eurekache: type of cached value is mismatched")
+)
+
+// WrapError returns error wrapping err with the kind of error.
+// errors.Is matches both of kind and err.
+func WrapError(kind, err error) error {
+	if err == nil {
+		return kind
+	}
+	return &wrappedError{
+		kind: kind,
+		err:  err,
+	}
+}
+
+// WrapConnError wraps error from the connection to cache source with ErrTimeout or ErrConnection.
+// context.DeadlineExceeded and timeout of net.Error are regarded as ErrTimeout.
+func WrapConnError(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return WrapError(ErrTimeout, err)
+	}
+	return WrapError(ErrConnection, err)
+}
+
+// wrappedError is an error with the kind of error
+type wrappedError struct {
+	kind error
+	err  error
+}
+
+// Error returns error message.
+func (e *wrappedError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+// Unwrap returns the original error.
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
+
+// Is checks if target is the kind of error or not.
+func (e *wrappedError) Is(target error) bool {
+	return target == e.kind
+}
@@ -0,0 +1,51 @@
+package eurekache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapError(t *testing.T) {
+	assert := assert.New(t)
+
+	errOriginal := errors.New("original error")
+	err := WrapError(ErrDecode, errOriginal)
+	assert.EqualError(err, "eurekache: failed to decode cached data: original error")
+	assert.True(errors.Is(err, ErrDecode))
+	assert.True(errors.Is(err, errOriginal))
+	assert.False(errors.Is(err, ErrTimeout))
+
+	// without original error
+	err = WrapError(ErrTimeout, nil)
+	assert.Equal(ErrTimeout, err)
+
+	// CopyError
+	err = CopyValueWithError(nil, "value")
+	assert.True(errors.Is(err, ErrTypeMismatch))
+}
+
+func TestWrapConnError(t *testing.T) {
+	assert := assert.New(t)
+
+	err := WrapConnError(context.DeadlineExceeded)
+	assert.True(errors.Is(err, ErrTimeout))
+
+	_, err = net.DialTimeout("tcp", "127.0.0.1:1", 0)
+	assert.Error(err)
+	err = WrapConnError(err)
+	assert.True(errors.Is(err, ErrConnection))
+	assert.False(errors.Is(err, ErrTimeout))
+
+	err = WrapConnError(&net.OpError{Op: "read", Err: timeoutError{}})
+	assert.True(errors.Is(err, ErrTimeout))
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
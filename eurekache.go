@@ -2,6 +2,7 @@
 package eurekache
 
 import (
+	"context"
 	"reflect"
 	"time"
 )
@@ -16,18 +17,40 @@ type Cache interface {
 	Clear() error
 }
 
+// Lookuper is interface for cache source distinguishing cache miss from failure
+type Lookuper interface {
+	Lookup(string, interface{}) (bool, error)
+}
+
+// ContextLookuper is interface for cache source stopping the search when the context is done
+type ContextLookuper interface {
+	LookupContext(context.Context, string, interface{}) (bool, error)
+}
+
 // Eurekache will contains multiple cache source
 type Eurekache struct {
 	caches       []Cache
 	readTimeout  time.Duration
 	writeTimeout time.Duration
+	jitter       Jitter
+	notifier     Notifier
+
+	readStrategy ReadStrategy
+	hedgeDelay   time.Duration
+
+	writeStrategy     WriteStrategy
+	asyncErrorHandler func(string, error)
+	asyncWriter       asyncWriter
+
+	healthTimeout time.Duration
 }
 
 // New returns empty new Eurekache
 func New() *Eurekache {
 	return &Eurekache{
-		readTimeout:  time.Hour,
-		writeTimeout: time.Hour,
+		readTimeout:   time.Hour,
+		writeTimeout:  time.Hour,
+		healthTimeout: defaultHealthTimeout,
 	}
 }
 
@@ -61,117 +84,122 @@ func (e *Eurekache) SetWriteTimeout(d time.Duration) {
 	e.writeTimeout = d
 }
 
+// SetJitter sets jitter for TTL given on SetExpire
+func (e *Eurekache) SetJitter(j Jitter) {
+	e.jitter = j
+}
+
 // Get searches cache by given key and returns flag of cache is existed or not.
 // when cache hit, data is assigned.
-func (e *Eurekache) Get(key string, data interface{}) (ok bool) {
-	ch := make(chan bool, 1)
-	// get cache
-	go func() {
-		for _, c := range e.caches {
-			ok = c.Get(key, data)
-			if ok {
-				ch <- true
-				return
-			}
-		}
-		ch <- false
-	}()
+func (e *Eurekache) Get(key string, data interface{}) bool {
+	ok, _ := e.Lookup(key, data)
+	return ok
+}
 
-	// get cache or timeout
-	select {
-	case <-ch:
-		return
-	case <-time.After(e.readTimeout):
-		return false
+// Lookup searches cache by given key and returns flag of cache is existed or not, and error.
+// When cache hit, data is assigned and error is nil even if the former cache sources failed.
+// When cache miss, the first error of cache sources is returned and nil means genuine miss.
+// ErrTimeout is returned when read timeout is reached.
+func (e *Eurekache) Lookup(key string, data interface{}) (bool, error) {
+	return e.LookupContext(context.Background(), key, data)
+}
+
+// LookupContext searches cache by given key like Lookup, and stops searching when ctx is done.
+func (e *Eurekache) LookupContext(ctx context.Context, key string, data interface{}) (bool, error) {
+	if _, ok := newDestination(data); !ok {
+		return false, CopyValueWithError(data, nil)
+	}
+
+	// each cache source has own destination for concurrent reads
+	dsts := make([]interface{}, len(e.caches))
+
+	var index int
+	var firstErr error
+	err := withTimeout(ctx, e.readTimeout, func(ctx context.Context) {
+		index, firstErr = e.readSources(ctx, func(ctx context.Context, i int, c Cache) (bool, error) {
+			dsts[i], _ = newDestination(data)
+			return lookupContext(ctx, c, key, dsts[i])
+		})
+	})
+
+	switch {
+	case err != nil:
+		return false, err
+	case index < 0:
+		return false, firstErr
+	}
+
+	assignDestination(data, dsts[index])
+	return true, nil
+}
+
+// lookup searches cache from the cache source, and Get is used when the source does not implement Lookuper
+func lookup(c Cache, key string, data interface{}) (bool, error) {
+	if l, ok := c.(Lookuper); ok {
+		return l.Lookup(key, data)
 	}
+	return c.Get(key, data), nil
+}
+
+// lookupContext searches cache from the cache source with ctx when the source implements ContextLookuper
+func lookupContext(ctx context.Context, c Cache, key string, data interface{}) (bool, error) {
+	if l, ok := c.(ContextLookuper); ok {
+		return l.LookupContext(ctx, key, data)
+	}
+	return lookup(c, key, data)
 }
 
 // GetInterface searches cache by given key and returns interface value.
-func (e *Eurekache) GetInterface(key string) (v interface{}, ok bool) {
-	ch := make(chan bool, 1)
-	// get cache
-	go func() {
-		for _, c := range e.caches {
-			v, ok = c.GetInterface(key)
-			if ok {
-				ch <- true
-				return
-			}
-		}
-		ch <- false
-	}()
+func (e *Eurekache) GetInterface(key string) (interface{}, bool) {
+	values := make([]interface{}, len(e.caches))
+	index := -1
+	err := withTimeout(context.Background(), e.readTimeout, func(ctx context.Context) {
+		index, _ = e.readSources(ctx, func(ctx context.Context, i int, c Cache) (bool, error) {
+			var ok bool
+			values[i], ok = c.GetInterface(key)
+			return ok, nil
+		})
+	})
 
-	// get cache or timeout
-	select {
-	case <-ch:
-		return
-	case <-time.After(e.readTimeout):
+	if err != nil || index < 0 {
 		return nil, false
 	}
+	return values[index], true
 }
 
 // GetGobBytes searches cache by given key and returns gob-encoded value.
-func (e *Eurekache) GetGobBytes(key string) (b []byte, ok bool) {
-	ch := make(chan bool, 1)
-	// get cache
-	go func() {
-		for _, c := range e.caches {
-			b, ok = c.GetGobBytes(key)
-			if ok {
-				ch <- true
-				return
-			}
-		}
-		ch <- false
-	}()
+func (e *Eurekache) GetGobBytes(key string) ([]byte, bool) {
+	values := make([][]byte, len(e.caches))
+	index := -1
+	err := withTimeout(context.Background(), e.readTimeout, func(ctx context.Context) {
+		index, _ = e.readSources(ctx, func(ctx context.Context, i int, c Cache) (bool, error) {
+			var ok bool
+			values[i], ok = c.GetGobBytes(key)
+			return ok, nil
+		})
+	})
 
-	// get cache or timeout
-	select {
-	case <-ch:
-		return
-	case <-time.After(e.readTimeout):
+	if err != nil || index < 0 {
 		return nil, false
 	}
+	return values[index], true
 }
 
 // Set sets data into all of cache sources.
-func (e *Eurekache) Set(key string, data interface{}) {
-	ch := make(chan bool, 1)
-	// set cache
-	go func() {
-		for _, c := range e.caches {
-			c.Set(key, data)
-		}
-		ch <- true
-	}()
-
-	// set cache or timeout
-	select {
-	case <-ch:
-		return
-	case <-time.After(e.writeTimeout):
-		return
-	}
+// *WriteError is returned when some of cache sources failed, and ErrTimeout is returned when write timeout is reached.
+func (e *Eurekache) Set(key string, data interface{}) error {
+	return e.write(key, func(c Cache) error {
+		return c.Set(key, data)
+	})
 }
 
 // SetExpire sets data with TTL.
-func (e *Eurekache) SetExpire(key string, data interface{}, ttl int64) {
-	ch := make(chan bool, 1)
-	// set cache
-	go func() {
-		for _, c := range e.caches {
-			c.SetExpire(key, data, ttl)
-		}
-		ch <- true
-	}()
-
-	// set cache or timeout
-	select {
-	case <-ch:
-		return
-	case <-time.After(e.writeTimeout):
-		return
-	}
+// When jitter is set, the same randomized TTL is used for all of cache sources.
+func (e *Eurekache) SetExpire(key string, data interface{}, ttl int64) error {
+	ttl = e.jitter.Apply(ttl)
+	return e.write(key, func(c Cache) error {
+		return c.SetExpire(key, data, ttl)
+	})
 }
 
 // ClearAll deletes all of cached data from cache sorces.
@@ -181,39 +209,84 @@ func (e *Eurekache) ClearAll() error {
 			return err
 		}
 	}
+	e.notifyClear()
 	return nil
 }
 
 // CopyValue copies src value into dst.
 func CopyValue(dst, src interface{}) bool {
+	return CopyValueWithError(dst, src) == nil
+}
+
+// CopyValueWithError copies src value into dst and returns *CopyError when the copy is refused.
+// The value is copied when its type is assignable to dst (including interface),
+// or convertible with the same kind (e.g. named type MyInt to int).
+// Pointers of src are dereferenced, and pointer of dst is allocated if needed.
+func CopyValueWithError(dst, src interface{}) error {
 	vvDst := reflect.ValueOf(dst)
 	switch {
 	case vvDst.Kind() != reflect.Ptr:
 		// cannot assign value for non-pointer
-		return false
+		return newCopyError(dst, src, "destination must be a pointer")
 	case vvDst.IsNil():
 		// cannot assign value for nil
-		return false
+		return newCopyError(dst, src, "destination must not be nil")
 	}
 
 	vvDst = vvDst.Elem()
 	if !vvDst.CanSet() {
-		return false
+		return newCopyError(dst, src, "destination cannot be set")
 	}
 
-	vvSrc := reflect.ValueOf(src)
-	if vvSrc.Kind() == reflect.Ptr {
-		vvSrc = vvSrc.Elem()
+	if src == nil {
+		if !canBeNil(vvDst) {
+			return newCopyError(dst, src, "nil cannot be assigned to non-nillable type")
+		}
+		vvDst.Set(reflect.Zero(vvDst.Type()))
+		return nil
 	}
 
-	// type check
+	reason := copyReflectValue(vvDst, reflect.ValueOf(src))
+	if reason != "" {
+		return newCopyError(dst, src, reason)
+	}
+	return nil
+}
+
+// copyReflectValue copies src into dst and returns the reason when the copy is refused
+func copyReflectValue(dst, src reflect.Value) string {
 	switch {
-	case vvSrc.Kind() != vvDst.Kind():
-		return false
-	case vvDst.Type() != vvSrc.Type():
-		return false
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+		return ""
+	case src.Kind() == reflect.Ptr || src.Kind() == reflect.Interface:
+		if src.IsNil() {
+			if !canBeNil(dst) {
+				return "nil pointer cannot be assigned to non-nillable type"
+			}
+			dst.Set(reflect.Zero(dst.Type()))
+			return ""
+		}
+		return copyReflectValue(dst, src.Elem())
+	case dst.Kind() == reflect.Ptr:
+		v := reflect.New(dst.Type().Elem())
+		reason := copyReflectValue(v.Elem(), src)
+		if reason == "" {
+			dst.Set(v)
+		}
+		return reason
+	case src.Kind() == dst.Kind() && src.Type().ConvertibleTo(dst.Type()):
+		dst.Set(src.Convert(dst.Type()))
+		return ""
 	}
+	return "type mismatch"
+}
 
-	vvDst.Set(vvSrc)
-	return true
+// canBeNil checks if nil can be assigned to the value or not
+func canBeNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func:
+		return true
+	}
+	return false
 }
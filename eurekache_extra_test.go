@@ -161,3 +161,27 @@ func TestExtraEurekacheSetExpire(t *testing.T) {
 	assert.False(ok)
 	assert.Empty(result)
 }
+
+func TestExtraEurekacheTags(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	m1 := memorycache.NewCacheTTL(10)
+	m2 := memorycache.NewCacheTTL(10)
+	e.SetCacheSources([]Cache{m1, m2})
+
+	e.SetWithTags("key1", "value1", 0, "tag1")
+	e.SetWithTags("key2", "value2", 0, "tag2")
+
+	var result string
+	assert.True(m1.Get("key1", &result))
+	assert.True(m2.Get("key1", &result))
+
+	err := e.InvalidateTag("tag1")
+	assert.NoError(err)
+	assert.False(m1.Get("key1", &result))
+	assert.False(m2.Get("key1", &result))
+	assert.False(e.Get("key1", &result))
+	assert.True(e.Get("key2", &result))
+	assert.Equal("value2", result)
+}
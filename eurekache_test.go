@@ -1,7 +1,9 @@
 package eurekache
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -46,6 +48,39 @@ func TestAddCacheSource(t *testing.T) {
 	assert.Equal(m2, e.caches[1])
 }
 
+func TestSetJitter(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	assert.Equal(Jitter{}, e.jitter)
+
+	j := Jitter{Percent: 0.1, Range: 1000}
+	e.SetJitter(j)
+	assert.Equal(j, e.jitter)
+}
+
+func TestSetNotifier(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	assert.Nil(e.notifier)
+
+	n := &dummyNotifier{}
+	e.SetNotifier(n)
+	assert.Equal(n, e.notifier)
+
+	e.Set("key1", "value")
+	e.SetExpire("key2", "value", 100)
+	e.SetWithTags("key3", "value", 100, "tag")
+	assert.Equal([]string{"key1", "key2", "key3"}, n.deleted)
+
+	e.InvalidateTag("tag")
+	assert.Equal([]string{"tag"}, n.tags)
+
+	e.ClearAll()
+	assert.Equal(1, n.cleared)
+}
+
 func TestGet(t *testing.T) {
 	assert := assert.New(t)
 
@@ -60,6 +95,53 @@ func TestGet(t *testing.T) {
 	assert.Empty(result)
 }
 
+func TestLookup(t *testing.T) {
+	assert := assert.New(t)
+
+	errSource := errors.New("source error")
+
+	// miss
+	e := New()
+	e.SetCacheSources([]Cache{newDummyCache()})
+	var result string
+	ok, err := e.Lookup("key", &result)
+	assert.False(ok)
+	assert.NoError(err)
+
+	// failure
+	e = New()
+	e.SetCacheSources([]Cache{
+		&dummyLookupCache{err: errSource},
+		newDummyCache(),
+		&dummyLookupCache{err: ErrTimeout},
+	})
+	ok, err = e.Lookup("key", &result)
+	assert.False(ok)
+	assert.Equal(errSource, err)
+
+	// hit after failure
+	e = New()
+	e.SetCacheSources([]Cache{
+		&dummyLookupCache{err: errSource},
+		&dummyLookupCache{value: "value"},
+	})
+	ok, err = e.Lookup("key", &result)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("value", result)
+
+	// timeout
+	e = New()
+	e.SetReadTimeout(10 * time.Millisecond)
+	e.SetCacheSources([]Cache{
+		&dummyLookupCache{value: "value", sleep: 100 * time.Millisecond},
+	})
+	var result2 string
+	ok, err = e.Lookup("key", &result2)
+	assert.False(ok)
+	assert.Equal(ErrTimeout, err)
+}
+
 func TestGetInterface(t *testing.T) {
 	assert := assert.New(t)
 
@@ -141,6 +223,93 @@ func TestCopyValue(t *testing.T) {
 	assert.Equal(valStruct1, valStruct3)
 }
 
+type copyTestInt int
+
+type copyTestUser struct {
+	Name string
+}
+
+func TestCopyValueWithError(t *testing.T) {
+	assert := assert.New(t)
+
+	var err error
+
+	// invalid destination
+	var valStr string
+	err = CopyValueWithError(valStr, "val")
+	assert.EqualError(err, "eurekache: cannot copy string into string: destination must be a pointer")
+	var valNilPtr *string
+	err = CopyValueWithError(valNilPtr, "val")
+	assert.EqualError(err, "eurekache: cannot copy string into *string: destination must not be nil")
+
+	// type mismatch
+	var valInt int
+	err = CopyValueWithError(&valInt, "val")
+	assert.EqualError(err, "eurekache: cannot copy string into *int: type mismatch")
+	copyErr, ok := err.(*CopyError)
+	assert.True(ok)
+	assert.Equal("type mismatch", copyErr.Reason)
+
+	// int must not be converted into string
+	err = CopyValueWithError(&valStr, 65)
+	assert.Error(err)
+	assert.Empty(valStr)
+
+	// convertible type
+	err = CopyValueWithError(&valInt, copyTestInt(10))
+	assert.NoError(err)
+	assert.Equal(10, valInt)
+	var valMyInt copyTestInt
+	err = CopyValueWithError(&valMyInt, 20)
+	assert.NoError(err)
+	assert.Equal(copyTestInt(20), valMyInt)
+
+	// interface destination
+	user := &copyTestUser{Name: "alice"}
+	var valIface interface{}
+	err = CopyValueWithError(&valIface, user)
+	assert.NoError(err)
+	assert.Equal(user, valIface)
+	var valStringer interface{ Error() string }
+	err = CopyValueWithError(&valStringer, user)
+	assert.Error(err)
+
+	// pointer destination
+	var valUserPtr *copyTestUser
+	err = CopyValueWithError(&valUserPtr, user)
+	assert.NoError(err)
+	assert.Equal(user, valUserPtr)
+	valUserPtr = nil
+	err = CopyValueWithError(&valUserPtr, *user)
+	assert.NoError(err)
+	assert.Equal(user, valUserPtr)
+	var valIntPtr *int
+	err = CopyValueWithError(&valIntPtr, copyTestInt(30))
+	assert.NoError(err)
+	assert.Equal(30, *valIntPtr)
+
+	// nested pointer
+	userPtr := &user
+	var valUser copyTestUser
+	err = CopyValueWithError(&valUser, &userPtr)
+	assert.NoError(err)
+	assert.Equal(*user, valUser)
+
+	// nil pointer
+	valUserPtr = user
+	err = CopyValueWithError(&valUserPtr, (*copyTestUser)(nil))
+	assert.NoError(err)
+	assert.Nil(valUserPtr)
+	err = CopyValueWithError(&valUser, (*copyTestUser)(nil))
+	assert.EqualError(err, "eurekache: cannot copy *eurekache.copyTestUser into *eurekache.copyTestUser: nil pointer cannot be assigned to non-nillable type")
+	valIface = 1
+	err = CopyValueWithError(&valIface, nil)
+	assert.NoError(err)
+	assert.Nil(valIface)
+	err = CopyValueWithError(&valInt, nil)
+	assert.Error(err)
+}
+
 type dummyCache struct{}
 
 func (d *dummyCache) Get(k string, v interface{}) bool {
@@ -170,3 +339,39 @@ func (d *dummyCache) Clear() error {
 func newDummyCache() *dummyCache {
 	return &dummyCache{}
 }
+
+type dummyNotifier struct {
+	deleted []string
+	tags    []string
+	cleared int
+}
+
+func (d *dummyNotifier) NotifyDelete(k string) error {
+	d.deleted = append(d.deleted, k)
+	return nil
+}
+
+func (d *dummyNotifier) NotifyClear() error {
+	d.cleared++
+	return nil
+}
+
+func (d *dummyNotifier) NotifyInvalidateTag(tag string) error {
+	d.tags = append(d.tags, tag)
+	return nil
+}
+
+type dummyLookupCache struct {
+	dummyCache
+	value string
+	err   error
+	sleep time.Duration
+}
+
+func (d *dummyLookupCache) Lookup(k string, v interface{}) (bool, error) {
+	time.Sleep(d.sleep)
+	if d.err != nil {
+		return false, d.err
+	}
+	return CopyValue(v, d.value), nil
+}
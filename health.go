@@ -0,0 +1,113 @@
+package eurekache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultHealthTimeout = time.Second
+
+// Pinger is interface for cache source checking its reachability
+type Pinger interface {
+	Ping(context.Context) error
+}
+
+// HealthReport contains the health of cache sources
+type HealthReport struct {
+	// true when all of cache sources are healthy
+	Healthy bool `json:"healthy"`
+
+	Sources []SourceHealth `json:"sources"`
+}
+
+// SourceHealth contains the health of a cache source.
+// The cache source not implementing Pinger is always healthy.
+type SourceHealth struct {
+	Index   int           `json:"index"`
+	Name    string        `json:"name"`
+	Healthy bool          `json:"healthy"`
+	Err     error         `json:"-"`
+	Error   string        `json:"error,omitempty"`
+	Latency time.Duration `json:"latency"`
+}
+
+// SetHealthTimeout sets timeout of pinging each cache source on Health. (default: 1s)
+func (e *Eurekache) SetHealthTimeout(d time.Duration) {
+	e.healthTimeout = d
+}
+
+// Health pings all of cache sources in parallel until health timeout, and returns the report.
+func (e *Eurekache) Health(ctx context.Context) *HealthReport {
+	caches := e.caches
+	report := &HealthReport{
+		Healthy: true,
+		Sources: make([]SourceHealth, len(caches)),
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range caches {
+		wg.Add(1)
+		go func(i int, c Cache) {
+			defer wg.Done()
+			report.Sources[i] = pingSource(ctx, e.healthTimeout, i, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, s := range report.Sources {
+		if !s.Healthy {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+// HealthHandler returns http.Handler responding the health report in JSON.
+// The status code is 200 when all of cache sources are healthy, otherwise 503.
+func (e *Eurekache) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := e.Health(r.Context())
+
+		status := http.StatusOK
+		if !report.Healthy {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	})
+}
+
+// pingSource pings the cache source until the timeout and returns the health
+func pingSource(ctx context.Context, timeout time.Duration, index int, c Cache) SourceHealth {
+	h := SourceHealth{
+		Index:   index,
+		Name:    sourceName(c),
+		Healthy: true,
+	}
+
+	p, ok := c.(Pinger)
+	if !ok {
+		return h
+	}
+
+	start := time.Now()
+	var pingErr error
+	err := withTimeout(ctx, timeout, func(ctx context.Context) {
+		pingErr = p.Ping(ctx)
+	})
+	if err == nil {
+		err = pingErr
+	}
+	h.Latency = time.Since(start)
+	if err != nil {
+		h.Healthy = false
+		h.Err = err
+		h.Error = err.Error()
+	}
+	return h
+}
@@ -0,0 +1,105 @@
+package eurekache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	assert := assert.New(t)
+
+	errSource := errors.New("source error")
+	e := New()
+	e.SetCacheSources([]Cache{
+		&dummyPingCache{},
+		&dummyCache{},
+	})
+
+	report := e.Health(context.Background())
+	assert.True(report.Healthy)
+	assert.Len(report.Sources, 2)
+	assert.Equal("*eurekache.dummyPingCache", report.Sources[0].Name)
+	assert.True(report.Sources[0].Healthy)
+	assert.True(report.Sources[1].Healthy, "source without Pinger is healthy")
+
+	// failure
+	e.SetCacheSources([]Cache{
+		&dummyPingCache{},
+		&dummyPingCache{err: errSource},
+	})
+	report = e.Health(context.Background())
+	assert.False(report.Healthy)
+	assert.True(report.Sources[0].Healthy)
+	assert.False(report.Sources[1].Healthy)
+	assert.Equal(errSource, report.Sources[1].Err)
+	assert.Equal("source error", report.Sources[1].Error)
+
+	// timeout
+	e.SetCacheSources([]Cache{
+		&dummyPingCache{sleep: 100 * time.Millisecond},
+	})
+	assert.Equal(time.Second, e.healthTimeout)
+	e.SetHealthTimeout(10 * time.Millisecond)
+	report = e.Health(context.Background())
+	assert.False(report.Healthy)
+	assert.Equal(ErrTimeout, report.Sources[0].Err)
+	assert.True(report.Sources[0].Latency < 90*time.Millisecond)
+}
+
+func TestHealthHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	e.SetCacheSources([]Cache{&dummyPingCache{}})
+
+	w := httptest.NewRecorder()
+	e.HealthHandler().ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("application/json", w.Header().Get("Content-Type"))
+
+	var report HealthReport
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &report))
+	assert.True(report.Healthy)
+	assert.Len(report.Sources, 1)
+
+	// unhealthy
+	e.SetCacheSources([]Cache{&dummyPingCache{err: ErrConnection}})
+	w = httptest.NewRecorder()
+	e.HealthHandler().ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	assert.Equal(http.StatusServiceUnavailable, w.Code)
+
+	report = HealthReport{}
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &report))
+	assert.False(report.Healthy)
+	assert.Equal(ErrConnection.Error(), report.Sources[0].Error)
+}
+
+func TestPingWrappedSource(t *testing.T) {
+	assert := assert.New(t)
+
+	src := &dummyPingCache{err: ErrConnection}
+	cb := NewCircuitBreakerCache(src)
+	assert.Equal(ErrConnection, cb.Ping(context.Background()))
+
+	wb := NewWriteBehindCache(src, 1)
+	defer wb.Close()
+	assert.Equal(ErrConnection, wb.Ping(context.Background()))
+}
+
+type dummyPingCache struct {
+	dummyCache
+	sleep time.Duration
+	err   error
+}
+
+func (d *dummyPingCache) Ping(ctx context.Context) error {
+	time.Sleep(d.sleep)
+	return d.err
+}
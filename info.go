@@ -0,0 +1,143 @@
+package eurekache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ItemLookuper is interface for cache source returning Item with meta data
+type ItemLookuper interface {
+	LookupItem(context.Context, string, interface{}) (*Item, bool, error)
+}
+
+// Namer is interface for cache source having its name
+type Namer interface {
+	Name() string
+}
+
+// GetInfo contains the details of reading data from cache sources
+type GetInfo struct {
+	// index of the cache source that hit, -1 when cache miss
+	Index int
+
+	// name of the cache source that hit
+	Name string
+
+	// unix nanosec of creation time and expires time of the hit item.
+	// they are zero when the cache source does not implement ItemLookuper.
+	CreatedAt int64
+	ExpiredAt int64
+
+	// results of the cache sources in searched order
+	Sources []SourceInfo
+}
+
+// SourceInfo contains the result of reading data from a cache source
+type SourceInfo struct {
+	Index   int
+	Name    string
+	Hit     bool
+	Err     error
+	Latency time.Duration
+}
+
+// GetWithInfo searches cache by given key like Lookup and returns the details of cache sources.
+// cache sources are searched sequentially regardless of the read strategy.
+func (e *Eurekache) GetWithInfo(key string, data interface{}) (bool, *GetInfo, error) {
+	info := &GetInfo{
+		Index: -1,
+	}
+
+	if _, ok := newDestination(data); !ok {
+		return false, info, CopyValueWithError(data, nil)
+	}
+
+	// sources is shared with the goroutine still running after timeout
+	var mu sync.Mutex
+	var sources []SourceInfo
+
+	var item *Item
+	var dst interface{}
+	err := withTimeout(context.Background(), e.readTimeout, func(ctx context.Context) {
+		for i, c := range e.caches {
+			if ctx.Err() != nil {
+				return
+			}
+
+			// each cache source has own destination to avoid leaking partially decoded value
+			d, _ := newDestination(data)
+			start := time.Now()
+			it, ok, err := lookupItem(ctx, c, key, d)
+
+			mu.Lock()
+			sources = append(sources, SourceInfo{
+				Index:   i,
+				Name:    sourceName(c),
+				Hit:     ok,
+				Err:     err,
+				Latency: time.Since(start),
+			})
+			mu.Unlock()
+
+			if ok {
+				item, dst = it, d
+				return
+			}
+		}
+	})
+
+	mu.Lock()
+	info.Sources = append([]SourceInfo(nil), sources...)
+	mu.Unlock()
+
+	if err != nil {
+		return false, info, err
+	}
+	if item != nil {
+		assignDestination(data, dst)
+	}
+	return info.result(item)
+}
+
+// result returns the result of GetWithInfo
+func (info *GetInfo) result(item *Item) (bool, *GetInfo, error) {
+	if item == nil {
+		for _, s := range info.Sources {
+			if s.Err != nil {
+				return false, info, s.Err
+			}
+		}
+		return false, info, nil
+	}
+
+	last := info.Sources[len(info.Sources)-1]
+	info.Index = last.Index
+	info.Name = last.Name
+	info.CreatedAt = item.CreatedAt
+	info.ExpiredAt = item.ExpiredAt
+	return true, info, nil
+}
+
+// lookupItem searches cache from the cache source and returns Item.
+// LookupContext, Lookup or Get is used when the source does not implement ItemLookuper, and returned Item has no meta data.
+func lookupItem(ctx context.Context, c Cache, key string, data interface{}) (*Item, bool, error) {
+	if l, ok := c.(ItemLookuper); ok {
+		return l.LookupItem(ctx, key, data)
+	}
+
+	ok, err := lookupContext(ctx, c, key, data)
+	if !ok {
+		return nil, false, err
+	}
+	return &Item{}, true, nil
+}
+
+// sourceName returns the name of the cache source
+func sourceName(c Cache) string {
+	if n, ok := c.(Namer); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", c)
+}
@@ -0,0 +1,124 @@
+package eurekache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetWithInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	errSource := errors.New("source error")
+
+	// miss
+	e := New()
+	e.SetCacheSources([]Cache{
+		newDummyCache(),
+		&dummyLookupCache{err: errSource},
+	})
+
+	var result string
+	ok, info, err := e.GetWithInfo("key", &result)
+	assert.False(ok)
+	assert.Equal(errSource, err)
+	assert.Equal(-1, info.Index)
+	assert.Empty(info.Name)
+	assert.Len(info.Sources, 2)
+	assert.Equal("*eurekache.dummyCache", info.Sources[0].Name)
+	assert.False(info.Sources[0].Hit)
+	assert.NoError(info.Sources[0].Err)
+	assert.Equal(1, info.Sources[1].Index)
+	assert.Equal(errSource, info.Sources[1].Err)
+
+	// hit
+	item := &Item{CreatedAt: 1, ExpiredAt: 2}
+	e = New()
+	e.SetCacheSources([]Cache{
+		&dummyLookupCache{err: errSource},
+		&dummyLookupCache{value: "value", sleep: 10 * time.Millisecond},
+		&dummyItemCache{item: item},
+	})
+	ok, info, err = e.GetWithInfo("key", &result)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("value", result)
+	assert.Equal(1, info.Index)
+	assert.EqualValues(0, info.CreatedAt)
+	assert.Len(info.Sources, 2)
+	assert.True(info.Sources[1].Hit)
+	assert.True(info.Sources[1].Latency >= 10*time.Millisecond)
+
+	// hit with meta data
+	e = New()
+	e.SetCacheSources([]Cache{
+		newDummyCache(),
+		&dummyItemCache{item: item},
+	})
+	ok, info, err = e.GetWithInfo("key", &result)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal(1, info.Index)
+	assert.Equal("dummy", info.Name)
+	assert.EqualValues(1, info.CreatedAt)
+	assert.EqualValues(2, info.ExpiredAt)
+
+	// timeout
+	e = New()
+	e.SetReadTimeout(50 * time.Millisecond)
+	e.SetCacheSources([]Cache{
+		newDummyCache(),
+		&dummyLookupCache{value: "value", sleep: 200 * time.Millisecond},
+	})
+	var result2 string
+	ok, info, err = e.GetWithInfo("key", &result2)
+	assert.False(ok)
+	assert.Equal(ErrTimeout, err)
+	assert.Equal(-1, info.Index)
+	assert.Len(info.Sources, 1)
+}
+
+func TestGetWithInfoPartialDecode(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	e.SetCacheSources([]Cache{
+		&dummyPartialCache{},
+		&dummyItemCache{item: &Item{}},
+	})
+
+	var result copyTestUser
+	ok, info, err := e.GetWithInfo("key", &result)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal(1, info.Index)
+	assert.Empty(result.Name, "partially decoded value from the failed source is not leaked")
+}
+
+// dummyPartialCache writes a part of data and fails like broken gob data
+type dummyPartialCache struct {
+	dummyCache
+}
+
+func (d *dummyPartialCache) Lookup(k string, v interface{}) (bool, error) {
+	if u, ok := v.(*copyTestUser); ok {
+		u.Name = "partial"
+	}
+	return false, ErrDecode
+}
+
+type dummyItemCache struct {
+	dummyCache
+	item *Item
+}
+
+func (d *dummyItemCache) Name() string {
+	return "dummy"
+}
+
+func (d *dummyItemCache) LookupItem(ctx context.Context, k string, v interface{}) (*Item, bool, error) {
+	return d.item, true, nil
+}
@@ -0,0 +1,75 @@
+// Package hashring provides consistent hash ring shared by the cache sources spreading keys across servers.
+package hashring
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// Ring is consistent hash ring placing each node at virtual points
+type Ring[T any] struct {
+	points []point[T]
+}
+
+// point is the virtual node in the ring
+type point[T any] struct {
+	hash uint64
+	name string
+	node T
+}
+
+// New returns Ring placing each node at vnodes points hashed from "name#i".
+// The keys of the map are unique names of the nodes.
+func New[T any](nodes map[string]T, vnodes int) *Ring[T] {
+	points := make([]point[T], 0, len(nodes)*vnodes)
+	for name, n := range nodes {
+		for i := 0; i < vnodes; i++ {
+			points = append(points, point[T]{
+				hash: Hash(name + "#" + strconv.Itoa(i)),
+				name: name,
+				node: n,
+			})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].hash == points[j].hash {
+			return points[i].name < points[j].name
+		}
+		return points[i].hash < points[j].hash
+	})
+	return &Ring[T]{points: points}
+}
+
+// Get returns the node of the first virtual point after the hash of the key,
+// and returns false when the ring is empty.
+func (r *Ring[T]) Get(key string) (T, bool) {
+	if r == nil || len(r.points) == 0 {
+		var zero T
+		return zero, false
+	}
+
+	h := Hash(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].node, true
+}
+
+// Hash returns 64bit FNV-1a hash of the key.
+// The hash is mixed by the finalizer of MurmurHash3 to spread similar keys over the ring.
+func Hash(key string) uint64 {
+	f := fnv.New64a()
+	f.Write([]byte(key))
+
+	h := f.Sum64()
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,49 @@
+package hashring
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing(t *testing.T) {
+	assert := assert.New(t)
+
+	// empty ring
+	_, ok := New(map[string]int{}, 10).Get("key")
+	assert.False(ok)
+	var nilRing *Ring[int]
+	_, ok = nilRing.Get("key")
+	assert.False(ok)
+
+	r := New(map[string]string{"a": "node-a", "b": "node-b", "c": "node-c"}, 160)
+	counts := make(map[string]int)
+	before := make(map[string]string)
+	for i := 0; i < 3000; i++ {
+		key := "key" + strconv.Itoa(i)
+		n, ok := r.Get(key)
+		assert.True(ok)
+		counts[n]++
+		before[key] = n
+	}
+	for _, n := range []string{"node-a", "node-b", "node-c"} {
+		assert.True(counts[n] > 500, n)
+	}
+
+	// only the keys of the removed node are remapped
+	r = New(map[string]string{"a": "node-a", "b": "node-b"}, 160)
+	for key, n := range before {
+		after, _ := r.Get(key)
+		if n != "node-c" {
+			assert.Equal(n, after, key)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(Hash("key"), Hash("key"))
+	assert.NotEqual(Hash("key1"), Hash("key2"))
+}
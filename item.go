@@ -1,6 +1,8 @@
 package eurekache
 
 import (
+	"bytes"
+	"encoding/gob"
 	"math"
 	"time"
 )
@@ -38,3 +40,62 @@ func (i *Item) SetExpire(ttl int64) {
 	}
 	i.ExpiredAt = i.CreatedAt + ttl*int64(time.Millisecond)
 }
+
+// EncodeItem wraps data by Item with TTL (milliseconds) and encodes it by encoding/gob.
+// It's used by the cache sources storing data as bytes.
+func EncodeItem(data interface{}, ttl int64) ([]byte, error) {
+	item := NewItem()
+	item.SetExpire(ttl)
+	item.Value = data
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(item)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeItem decodes Item encoded by EncodeItem
+func DecodeItem(b []byte) (*Item, error) {
+	var item Item
+	dec := gob.NewDecoder(bytes.NewBuffer(b))
+	err := dec.Decode(&item)
+	if err != nil {
+		return nil, WrapError(ErrDecode, err)
+	}
+	return &item, nil
+}
+
+// Assign assigns the value into data through encoding/gob
+func (i *Item) Assign(data interface{}) error {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(i.Value)
+	if err != nil {
+		return WrapError(ErrDecode, err)
+	}
+
+	dec := gob.NewDecoder(&buf)
+	err = dec.Decode(data)
+	if err != nil {
+		return WrapError(ErrDecode, err)
+	}
+	return nil
+}
+
+// GobBytes returns gob-encoded value, and returns false when the value is nil or cannot be encoded.
+func (i *Item) GobBytes() ([]byte, bool) {
+	if i.Value == nil {
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(i.Value)
+	if err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
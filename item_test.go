@@ -1,6 +1,7 @@
 package eurekache
 
 import (
+	"errors"
 	"math"
 	"testing"
 	"time"
@@ -44,3 +45,34 @@ func TestItemSetExpire(t *testing.T) {
 	item.SetExpire(100)
 	assert.EqualValues(item.CreatedAt+100*int64(time.Millisecond), item.ExpiredAt)
 }
+
+func TestEncodeItem(t *testing.T) {
+	assert := assert.New(t)
+
+	b, err := EncodeItem("value", 100)
+	assert.NoError(err)
+
+	item, err := DecodeItem(b)
+	assert.NoError(err)
+	assert.Equal("value", item.Value)
+	assert.EqualValues(item.CreatedAt+100*int64(time.Millisecond), item.ExpiredAt)
+
+	var v string
+	assert.NoError(item.Assign(&v))
+	assert.Equal("value", v)
+
+	var i int
+	assert.True(errors.Is(item.Assign(&i), ErrDecode))
+
+	gb, ok := item.GobBytes()
+	assert.True(ok)
+	assert.NotEmpty(gb)
+
+	// nil value
+	item.Value = nil
+	_, ok = item.GobBytes()
+	assert.False(ok)
+
+	_, err = DecodeItem([]byte("invalid"))
+	assert.True(errors.Is(err, ErrDecode))
+}
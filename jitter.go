@@ -0,0 +1,33 @@
+package eurekache
+
+import (
+	"math/rand"
+)
+
+// Jitter randomizes TTL to avoid synchronized expiry of many items.
+// The extra time is added to the given TTL, so items never expire earlier than requested.
+type Jitter struct {
+	// ratio of ttl used for maximum extra time (e.g. 0.1 adds up to 10% of ttl)
+	Percent float64
+
+	// maximum extra time (millisec)
+	Range int64
+}
+
+// Apply returns ttl added random extra time.
+// ttl less than 1 means no expiration and is returned as it is.
+func (j Jitter) Apply(ttl int64) int64 {
+	if ttl < 1 {
+		return ttl
+	}
+
+	max := j.Range
+	if j.Percent > 0 {
+		max += int64(float64(ttl) * j.Percent)
+	}
+	if max < 1 {
+		return ttl
+	}
+
+	return ttl + rand.Int63n(max+1)
+}
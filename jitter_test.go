@@ -0,0 +1,44 @@
+package eurekache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJitterApply(t *testing.T) {
+	assert := assert.New(t)
+
+	// no jitter
+	j := Jitter{}
+	assert.EqualValues(100, j.Apply(100))
+
+	// no expiration
+	j = Jitter{Percent: 0.5, Range: 100}
+	assert.EqualValues(0, j.Apply(0))
+	assert.EqualValues(-1, j.Apply(-1))
+
+	// percent
+	j = Jitter{Percent: 0.5}
+	for i := 0; i < 100; i++ {
+		ttl := j.Apply(1000)
+		assert.True(ttl >= 1000)
+		assert.True(ttl <= 1500)
+	}
+
+	// range
+	j = Jitter{Range: 200}
+	for i := 0; i < 100; i++ {
+		ttl := j.Apply(1000)
+		assert.True(ttl >= 1000)
+		assert.True(ttl <= 1200)
+	}
+
+	// percent and range
+	j = Jitter{Percent: 0.1, Range: 200}
+	for i := 0; i < 100; i++ {
+		ttl := j.Apply(1000)
+		assert.True(ttl >= 1000)
+		assert.True(ttl <= 1300)
+	}
+}
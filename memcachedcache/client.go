@@ -0,0 +1,474 @@
+package memcachedcache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evalphobia/eurekache/internal/hashring"
+)
+
+const (
+	defaultTimeout      = 500 * time.Millisecond
+	defaultMaxIdleConns = 2
+	virtualNodes        = 160
+	maxKeyLength        = 250
+)
+
+var (
+	errNoServer     = errors.New("no memcached server is available")
+	errInvalidKey   = errors.New("invalid memcached key")
+	errNotStored    = errors.New("memcached item is not stored")
+	errCASExists    = errors.New("memcached item is modified")
+	errCASNotFound  = errors.New("memcached item is not found")
+	errInvalidReply = errors.New("invalid memcached reply")
+	errClosed       = errors.New("memcached client is closed")
+)
+
+var (
+	replyStored    = []byte("STORED\r\n")
+	replyNotStored = []byte("NOT_STORED\r\n")
+	replyExists    = []byte("EXISTS\r\n")
+	replyNotFound  = []byte("NOT_FOUND\r\n")
+	replyDeleted   = []byte("DELETED\r\n")
+	replyEnd       = []byte("END\r\n")
+)
+
+// entry is the value stored in memcached
+type entry struct {
+	key   string
+	value []byte
+	cas   uint64
+}
+
+// server is memcached server having idle connections
+type server struct {
+	addr string
+
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+// conn is the connection to memcached server
+type conn struct {
+	nc  net.Conn
+	rw  *bufio.ReadWriter
+	srv *server
+}
+
+// client sends commands to memcached servers selected by consistent hashing
+type client struct {
+	servers []*server
+	ring    *hashring.Ring[*server]
+	timeout time.Duration
+	maxIdle int
+	dialer  net.Dialer
+}
+
+// newClient returns initialized client with the addresses of memcached servers
+func newClient(addrs []string) *client {
+	c := &client{
+		timeout: defaultTimeout,
+		maxIdle: defaultMaxIdleConns,
+	}
+	servers := make(map[string]*server, len(addrs))
+	for _, addr := range addrs {
+		if _, ok := servers[addr]; ok {
+			continue
+		}
+		srv := &server{addr: addr}
+		c.servers = append(c.servers, srv)
+		servers[addr] = srv
+	}
+	c.ring = hashring.New(servers, virtualNodes)
+	return c
+}
+
+// pick returns the server for the key
+func (c *client) pick(key string) (*server, error) {
+	srv, ok := c.ring.Get(key)
+	if !ok {
+		return nil, errNoServer
+	}
+	return srv, nil
+}
+
+// get sends get (or gets with CAS) to the servers of the keys and returns the entries cache hit
+func (c *client) get(ctx context.Context, keys []string, withCAS bool) (map[string]entry, error) {
+	groups := make(map[*server][]string)
+	for _, key := range keys {
+		if !validKey(key) {
+			return nil, errInvalidKey
+		}
+		srv, err := c.pick(key)
+		if err != nil {
+			return nil, err
+		}
+		groups[srv] = append(groups[srv], key)
+	}
+
+	cmd := "get"
+	if withCAS {
+		cmd = "gets"
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	result := make(map[string]entry, len(keys))
+	for srv, keys := range groups {
+		wg.Add(1)
+		go func(srv *server, keys []string) {
+			defer wg.Done()
+			err := c.withConn(ctx, srv, func(cn *conn) error {
+				_, err := fmt.Fprintf(cn.rw, "%s %s\r\n", cmd, strings.Join(keys, " "))
+				if err != nil {
+					return err
+				}
+				err = cn.rw.Flush()
+				if err != nil {
+					return err
+				}
+				return readValues(cn.rw.Reader, func(e entry) {
+					mu.Lock()
+					result[e.key] = e
+					mu.Unlock()
+				})
+			})
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(srv, keys)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
+
+// store sends set, add or cas and returns the error of the reply
+func (c *client) store(ctx context.Context, cmd, key string, value []byte, exptime int64, cas uint64) error {
+	if !validKey(key) {
+		return errInvalidKey
+	}
+	srv, err := c.pick(key)
+	if err != nil {
+		return err
+	}
+
+	return c.withConn(ctx, srv, func(cn *conn) error {
+		if cmd == "cas" {
+			_, err = fmt.Fprintf(cn.rw, "cas %s 0 %d %d %d\r\n", key, exptime, len(value), cas)
+		} else {
+			_, err = fmt.Fprintf(cn.rw, "%s %s 0 %d %d\r\n", cmd, key, exptime, len(value))
+		}
+		if err != nil {
+			return err
+		}
+		_, err = cn.rw.Write(value)
+		if err != nil {
+			return err
+		}
+		_, err = cn.rw.WriteString("\r\n")
+		if err != nil {
+			return err
+		}
+
+		line, err := readReply(cn.rw)
+		switch {
+		case err != nil:
+			return err
+		case bytes.Equal(line, replyStored):
+			return nil
+		case bytes.Equal(line, replyNotStored):
+			return errNotStored
+		case bytes.Equal(line, replyExists):
+			return errCASExists
+		case bytes.Equal(line, replyNotFound):
+			return errCASNotFound
+		}
+		return replyError(line)
+	})
+}
+
+// delete sends delete, and the key not found is ignored
+func (c *client) delete(ctx context.Context, key string) error {
+	if !validKey(key) {
+		return errInvalidKey
+	}
+	srv, err := c.pick(key)
+	if err != nil {
+		return err
+	}
+
+	return c.withConn(ctx, srv, func(cn *conn) error {
+		_, err := fmt.Fprintf(cn.rw, "delete %s\r\n", key)
+		if err != nil {
+			return err
+		}
+
+		line, err := readReply(cn.rw)
+		switch {
+		case err != nil:
+			return err
+		case bytes.Equal(line, replyDeleted),
+			bytes.Equal(line, replyNotFound):
+			return nil
+		}
+		return replyError(line)
+	})
+}
+
+// version sends version to all of the servers
+func (c *client) version(ctx context.Context) error {
+	if len(c.servers) == 0 {
+		return errNoServer
+	}
+
+	for _, srv := range c.servers {
+		err := c.withConn(ctx, srv, func(cn *conn) error {
+			_, err := cn.rw.WriteString("version\r\n")
+			if err != nil {
+				return err
+			}
+
+			line, err := readReply(cn.rw)
+			if err != nil {
+				return err
+			}
+			if !bytes.HasPrefix(line, []byte("VERSION ")) {
+				return replyError(line)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("memcached server %s: %w", srv.addr, err)
+		}
+	}
+	return nil
+}
+
+// close closes idle connections of all of the servers
+func (c *client) close() error {
+	for _, srv := range c.servers {
+		srv.mu.Lock()
+		for _, cn := range srv.idle {
+			cn.nc.Close()
+		}
+		srv.idle = nil
+		srv.closed = true
+		srv.mu.Unlock()
+	}
+	return nil
+}
+
+// withConn runs fn with the connection to the server until ctx is done or the timeout.
+// The connection is closed when fn returns error except the expected replies, or when ctx is done while fn is running.
+func (c *client) withConn(ctx context.Context, srv *server, fn func(*conn) error) error {
+	cn, err := c.getConn(ctx, srv)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	cn.nc.SetDeadline(deadline)
+
+	stop := watchContext(ctx, cn.nc)
+	err = fn(cn)
+	interrupted := stop()
+	switch {
+	case interrupted:
+		cn.nc.Close()
+		if err != nil {
+			return ctx.Err()
+		}
+		return nil
+	case err == nil || isReplyError(err):
+		c.putConn(cn)
+		return err
+	}
+
+	cn.nc.Close()
+	return err
+}
+
+// watchContext interrupts reading and writing of the connection when ctx is done.
+// The returned function stops watching, and reports whether the connection was interrupted.
+func watchContext(ctx context.Context, nc net.Conn) func() bool {
+	done := ctx.Done()
+	if done == nil {
+		return func() bool { return false }
+	}
+
+	stop := make(chan struct{})
+	interrupted := make(chan bool, 1)
+	go func() {
+		select {
+		case <-done:
+			// the deadline in the past makes blocking I/O fail immediately
+			nc.SetDeadline(time.Unix(1, 0))
+			interrupted <- true
+		case <-stop:
+			interrupted <- false
+		}
+	}()
+	return func() bool {
+		close(stop)
+		return <-interrupted
+	}
+}
+
+// getConn returns the idle connection or creates new connection
+func (c *client) getConn(ctx context.Context, srv *server) (*conn, error) {
+	srv.mu.Lock()
+	if srv.closed {
+		srv.mu.Unlock()
+		return nil, errClosed
+	}
+	if n := len(srv.idle); n > 0 {
+		cn := srv.idle[n-1]
+		srv.idle = srv.idle[:n-1]
+		srv.mu.Unlock()
+		return cn, nil
+	}
+	srv.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	nc, err := c.dialer.DialContext(ctx, "tcp", srv.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{
+		nc:  nc,
+		rw:  bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc)),
+		srv: srv,
+	}, nil
+}
+
+// putConn returns the connection to the idle connections of the server
+func (c *client) putConn(cn *conn) {
+	srv := cn.srv
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.closed || len(srv.idle) >= c.maxIdle {
+		cn.nc.Close()
+		return
+	}
+	srv.idle = append(srv.idle, cn)
+}
+
+// readReply flushes the request and reads the reply line
+func readReply(rw *bufio.ReadWriter) ([]byte, error) {
+	err := rw.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return rw.ReadSlice('\n')
+}
+
+// readValues reads VALUE lines until END
+func readValues(r *bufio.Reader, fn func(entry)) error {
+	for {
+		line, err := r.ReadSlice('\n')
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(line, replyEnd) {
+			return nil
+		}
+		if !bytes.HasPrefix(line, []byte("VALUE ")) {
+			return replyError(line)
+		}
+
+		// VALUE <key> <flags> <bytes> [<cas unique>]
+		fields := strings.Fields(string(line))
+		if len(fields) < 4 {
+			return errInvalidReply
+		}
+		size, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return errInvalidReply
+		}
+		e := entry{key: fields[1]}
+		if len(fields) > 4 {
+			e.cas, err = strconv.ParseUint(fields[4], 10, 64)
+			if err != nil {
+				return errInvalidReply
+			}
+		}
+
+		e.value = make([]byte, size+2)
+		_, err = io.ReadFull(r, e.value)
+		if err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(e.value, []byte("\r\n")) {
+			return errInvalidReply
+		}
+		e.value = e.value[:size]
+		fn(e)
+	}
+}
+
+// serverError is error reply from memcached server
+type serverError struct {
+	reply string
+}
+
+func (e *serverError) Error() string {
+	return "memcached: " + e.reply
+}
+
+// replyError returns error of the unexpected reply
+func replyError(line []byte) error {
+	reply := strings.TrimSpace(string(line))
+	switch {
+	case reply == "ERROR",
+		strings.HasPrefix(reply, "CLIENT_ERROR"),
+		strings.HasPrefix(reply, "SERVER_ERROR"):
+		return &serverError{reply: reply}
+	}
+	return errInvalidReply
+}
+
+// isReplyError checks the error is the expected reply of the command and the connection can be reused
+func isReplyError(err error) bool {
+	switch err {
+	case errNotStored, errCASExists, errCASNotFound:
+		return true
+	}
+	return false
+}
+
+// validKey checks the key is available on memcached
+func validKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,271 @@
+package memcachedcache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientPick(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := newClient(nil).pick("key")
+	assert.Equal(errNoServer, err)
+
+	c := newClient([]string{"server1:11211", "server2:11211", "server3:11211"})
+	counts := make(map[string]int)
+	for i := 0; i < 9000; i++ {
+		srv, err := c.pick("key" + strconv.Itoa(i))
+		assert.NoError(err)
+		counts[srv.addr]++
+	}
+	assert.Len(counts, 3)
+	for _, count := range counts {
+		assert.InDelta(3000, count, 900)
+	}
+}
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	c := newClient([]string{testMemcachedAddr(t)})
+	defer c.close()
+
+	assert.NoError(c.version(ctx))
+	assert.NoError(c.store(ctx, "set", "eurekache:client:key1", []byte("value1"), 0, 0))
+	assert.NoError(c.store(ctx, "set", "eurekache:client:key2", []byte("value\r\n2"), 10, 0))
+
+	entries, err := c.get(ctx, []string{"eurekache:client:key1", "eurekache:client:key2", "eurekache:client:nokey"}, true)
+	assert.NoError(err)
+	assert.Len(entries, 2)
+	assert.Equal([]byte("value1"), entries["eurekache:client:key1"].value)
+	assert.Equal([]byte("value\r\n2"), entries["eurekache:client:key2"].value)
+
+	// cas
+	cas := entries["eurekache:client:key1"].cas
+	assert.NotZero(cas)
+	assert.NoError(c.store(ctx, "cas", "eurekache:client:key1", []byte("value3"), 0, cas))
+	assert.Equal(errCASExists, c.store(ctx, "cas", "eurekache:client:key1", []byte("value4"), 0, cas))
+	assert.Equal(errNotStored, c.store(ctx, "add", "eurekache:client:key1", []byte("value4"), 0, 0))
+
+	assert.NoError(c.delete(ctx, "eurekache:client:key1"))
+	assert.NoError(c.delete(ctx, "eurekache:client:key1"))
+	assert.Equal(errCASNotFound, c.store(ctx, "cas", "eurekache:client:key1", []byte("value4"), 0, cas))
+
+	// invalid key
+	_, err = c.get(ctx, []string{"invalid key"}, false)
+	assert.Equal(errInvalidKey, err)
+	assert.Equal(errInvalidKey, c.store(ctx, "set", strings.Repeat("k", 251), nil, 0, 0))
+
+	// connection is reused
+	srv, _ := c.pick("eurekache:client:key1")
+	srv.mu.Lock()
+	assert.Len(srv.idle, 1)
+	srv.mu.Unlock()
+
+	c.close()
+	assert.Equal(errClosed, c.delete(ctx, "eurekache:client:key1"))
+}
+
+func TestClientCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	// server never replies
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	c := newClient([]string{l.Addr().String()})
+	c.timeout = 10 * time.Second
+	defer c.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = c.get(ctx, []string{"eurekache:client:key1"}, false)
+	assert.Equal(context.Canceled, err)
+	assert.Less(time.Since(start), time.Second)
+
+	// interrupted connection is not reused
+	srv, _ := c.pick("eurekache:client:key1")
+	srv.mu.Lock()
+	assert.Empty(srv.idle)
+	srv.mu.Unlock()
+}
+
+func TestValidKey(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(validKey("key"))
+	assert.True(validKey(strings.Repeat("k", 250)))
+	assert.False(validKey(""))
+	assert.False(validKey(strings.Repeat("k", 251)))
+	assert.False(validKey("invalid key"))
+	assert.False(validKey("key\n"))
+}
+
+// testMemcachedAddr returns the address of memcached from MEMCACHED_ADDR,
+// or starts in-memory fake server supporting a part of the text protocol.
+func testMemcachedAddr(t *testing.T) string {
+	if addr := os.Getenv("MEMCACHED_ADDR"); addr != "" {
+		return addr
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{
+		data: make(map[string]fakeValue),
+	}
+	go s.serve(l)
+	t.Cleanup(func() { l.Close() })
+	return l.Addr().String()
+}
+
+type fakeValue struct {
+	value     []byte
+	cas       uint64
+	expiredAt time.Time
+}
+
+type fakeServer struct {
+	mu   sync.Mutex
+	data map[string]fakeValue
+	cas  uint64
+}
+
+func (s *fakeServer) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			fmt.Fprint(w, "ERROR\r\n")
+			w.Flush()
+			continue
+		}
+
+		switch fields[0] {
+		case "get", "gets":
+			s.get(w, fields[0] == "gets", fields[1:])
+		case "set", "add", "cas":
+			size, _ := strconv.Atoi(fields[4])
+			value := make([]byte, size+2)
+			if _, err := io.ReadFull(r, value); err != nil {
+				return
+			}
+			exptime, _ := strconv.ParseInt(fields[3], 10, 64)
+			var cas uint64
+			if len(fields) > 5 {
+				cas, _ = strconv.ParseUint(fields[5], 10, 64)
+			}
+			fmt.Fprint(w, s.store(fields[0], fields[1], value[:size], exptime, cas))
+		case "delete":
+			fmt.Fprint(w, s.delete(fields[1]))
+		case "version":
+			fmt.Fprint(w, "VERSION fake\r\n")
+		default:
+			fmt.Fprint(w, "ERROR\r\n")
+		}
+		w.Flush()
+	}
+}
+
+func (s *fakeServer) get(w io.Writer, withCAS bool, keys []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		v, ok := s.lookup(key)
+		if !ok {
+			continue
+		}
+		if withCAS {
+			fmt.Fprintf(w, "VALUE %s 0 %d %d\r\n", key, len(v.value), v.cas)
+		} else {
+			fmt.Fprintf(w, "VALUE %s 0 %d\r\n", key, len(v.value))
+		}
+		w.Write(v.value)
+		fmt.Fprint(w, "\r\n")
+	}
+	fmt.Fprint(w, "END\r\n")
+}
+
+func (s *fakeServer) store(cmd, key string, value []byte, exptime int64, cas uint64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.lookup(key)
+	switch {
+	case cmd == "add" && ok:
+		return "NOT_STORED\r\n"
+	case cmd == "cas" && !ok:
+		return "NOT_FOUND\r\n"
+	case cmd == "cas" && current.cas != cas:
+		return "EXISTS\r\n"
+	}
+
+	s.cas++
+	v := fakeValue{value: value, cas: s.cas}
+	if exptime > 0 {
+		v.expiredAt = time.Now().Add(time.Duration(exptime) * time.Second)
+	}
+	s.data[key] = v
+	return "STORED\r\n"
+}
+
+func (s *fakeServer) delete(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lookup(key); !ok {
+		return "NOT_FOUND\r\n"
+	}
+	delete(s.data, key)
+	return "DELETED\r\n"
+}
+
+func (s *fakeServer) lookup(key string) (fakeValue, bool) {
+	v, ok := s.data[key]
+	if !ok || (!v.expiredAt.IsZero() && time.Now().After(v.expiredAt)) {
+		return fakeValue{}, false
+	}
+	return v, true
+}
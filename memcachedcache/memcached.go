@@ -0,0 +1,276 @@
+package memcachedcache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/evalphobia/eurekache"
+)
+
+// maxRelativeExpiration is max seconds of relative expiration time on memcached,
+// and longer expiration is sent as unix time.
+const maxRelativeExpiration = 60 * 60 * 24 * 30
+
+// ErrCASConflict is returned when the data is modified or deleted after LookupCAS.
+var ErrCASConflict = errors.New("cas conflict")
+
+// MemcachedCache is a cache source for memcached
+type MemcachedCache struct {
+	client     *client
+	prefix     string
+	defaultTTL int64
+	jitter     eurekache.Jitter
+	name       string
+}
+
+// NewMemcachedCache returns initialized MemcachedCache with the addresses (host:port) of memcached servers.
+// Keys are spread across the servers by consistent hashing.
+func NewMemcachedCache(addrs ...string) *MemcachedCache {
+	return &MemcachedCache{
+		client: newClient(addrs),
+		name:   "memcached",
+	}
+}
+
+// SetName sets the name of the cache source
+func (c *MemcachedCache) SetName(name string) {
+	c.name = name
+}
+
+// Name returns the name of the cache source
+func (c *MemcachedCache) Name() string {
+	return c.name
+}
+
+// SetTTL sets default TTL (milliseconds)
+func (c *MemcachedCache) SetTTL(ttl int64) {
+	c.defaultTTL = ttl
+}
+
+// SetJitter sets jitter for TTL
+func (c *MemcachedCache) SetJitter(j eurekache.Jitter) {
+	c.jitter = j
+}
+
+// SetPrefix sets the prefix used for adding prefix into key name
+func (c *MemcachedCache) SetPrefix(prefix string) {
+	c.prefix = prefix
+}
+
+// SetTimeout sets timeout of each command. (default: 500ms)
+func (c *MemcachedCache) SetTimeout(d time.Duration) {
+	c.client.timeout = d
+}
+
+// SetMaxIdleConns sets max number of idle connections for each server. (default: 2)
+func (c *MemcachedCache) SetMaxIdleConns(n int) {
+	c.client.maxIdle = n
+}
+
+// Ping checks the connection to all of the servers until the deadline of ctx
+func (c *MemcachedCache) Ping(ctx context.Context) error {
+	err := c.client.version(ctx)
+	if err != nil {
+		return wrapConnError(err)
+	}
+	return nil
+}
+
+// Close closes idle connections
+func (c *MemcachedCache) Close() error {
+	return c.client.close()
+}
+
+// Get searches cache by given key from memcached and returns flag of cache is existed or not.
+// when cache hit, data is assigned.
+func (c *MemcachedCache) Get(key string, data interface{}) bool {
+	ok, _ := c.Lookup(key, data)
+	return ok
+}
+
+// Lookup searches cache by given key from memcached and returns flag of cache is existed or not, and error.
+// when cache hit, data is assigned.
+func (c *MemcachedCache) Lookup(key string, data interface{}) (bool, error) {
+	_, ok, err := c.LookupItem(context.Background(), key, data)
+	return ok, err
+}
+
+// LookupContext searches cache like Lookup, and memcached command is timed out at the deadline of ctx.
+func (c *MemcachedCache) LookupContext(ctx context.Context, key string, data interface{}) (bool, error) {
+	_, ok, err := c.LookupItem(ctx, key, data)
+	return ok, err
+}
+
+// LookupItem searches cache by given key from memcached and returns Item and error.
+// when cache hit, data is assigned.
+func (c *MemcachedCache) LookupItem(ctx context.Context, key string, data interface{}) (*eurekache.Item, bool, error) {
+	item, _, ok, err := c.getItem(ctx, key, false)
+	if !ok {
+		return nil, false, err
+	}
+
+	err = item.Assign(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return item, true, nil
+}
+
+// LookupCAS searches cache like LookupContext, and returns CAS unique value for CompareAndSwap.
+// CAS value is zero on cache miss.
+func (c *MemcachedCache) LookupCAS(ctx context.Context, key string, data interface{}) (cas uint64, ok bool, err error) {
+	item, cas, ok, err := c.getItem(ctx, key, true)
+	if !ok {
+		return 0, false, err
+	}
+
+	err = item.Assign(data)
+	if err != nil {
+		return 0, false, err
+	}
+	return cas, true, nil
+}
+
+// GetInterface searches cache by given key from memcached and returns interface value.
+func (c *MemcachedCache) GetInterface(key string) (interface{}, bool) {
+	item, _, ok, _ := c.getItem(context.Background(), key, false)
+	if !ok {
+		return nil, false
+	}
+	return item.Value, true
+}
+
+// GetGobBytes searches cache by given key from memcached and returns gob-encoded value.
+func (c *MemcachedCache) GetGobBytes(key string) ([]byte, bool) {
+	item, _, ok, _ := c.getItem(context.Background(), key, false)
+	if !ok {
+		return nil, false
+	}
+	return item.GobBytes()
+}
+
+// GetMulti searches multiple cache by given keys from memcached at once, and returns values of the keys cache hit.
+// The keys are grouped by server and sent by a get command for each server.
+// Data failed to decode is regarded as cache miss.
+func (c *MemcachedCache) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+
+	entries, err := c.client.get(ctx, prefixed, false)
+	if err != nil {
+		return nil, wrapConnError(err)
+	}
+
+	result := make(map[string]interface{}, len(entries))
+	for i, key := range prefixed {
+		e, ok := entries[key]
+		if !ok {
+			continue
+		}
+		item, err := eurekache.DecodeItem(e.value)
+		if err != nil || item.Value == nil {
+			continue
+		}
+		result[keys[i]] = item.Value
+	}
+	return result, nil
+}
+
+// getItem sends get or gets to memcached and returns Item data, CAS unique value and error
+func (c *MemcachedCache) getItem(ctx context.Context, key string, withCAS bool) (*eurekache.Item, uint64, bool, error) {
+	entries, err := c.client.get(ctx, []string{c.prefix + key}, withCAS)
+	if err != nil {
+		return nil, 0, false, wrapConnError(err)
+	}
+
+	e, ok := entries[c.prefix+key]
+	if !ok {
+		return nil, 0, false, nil
+	}
+
+	item, err := eurekache.DecodeItem(e.value)
+	switch {
+	case err != nil:
+		return nil, 0, false, err
+	case item.Value == nil:
+		return nil, 0, false, nil
+	}
+	return item, e.cas, true, nil
+}
+
+// Set sets data into memcached. data is wrapped by gob-encoded Item
+func (c *MemcachedCache) Set(key string, data interface{}) error {
+	return c.SetExpire(key, data, c.defaultTTL)
+}
+
+// SetExpire sets data into memcached with TTL. data is wrapped by gob-encoded Item.
+// nil data is deleted.
+func (c *MemcachedCache) SetExpire(key string, data interface{}, ttl int64) error {
+	if data == nil {
+		return c.client.delete(context.Background(), c.prefix+key)
+	}
+
+	ttl = c.jitter.Apply(ttl)
+	b, err := eurekache.EncodeItem(data, ttl)
+	if err != nil {
+		return err
+	}
+	return c.client.store(context.Background(), "set", c.prefix+key, b, expiration(ttl), 0)
+}
+
+// CompareAndSwap sets data into memcached with TTL only when the data is not changed after LookupCAS.
+// When cas is zero, data is set only when the key does not exist.
+// ErrCASConflict is returned when the data is modified or deleted by others.
+func (c *MemcachedCache) CompareAndSwap(key string, data interface{}, ttl int64, cas uint64) error {
+	ttl = c.jitter.Apply(ttl)
+	b, err := eurekache.EncodeItem(data, ttl)
+	if err != nil {
+		return err
+	}
+
+	cmd := "cas"
+	if cas == 0 {
+		cmd = "add"
+	}
+	err = c.client.store(context.Background(), cmd, c.prefix+key, b, expiration(ttl), cas)
+	switch err {
+	case errCASExists, errCASNotFound, errNotStored:
+		return ErrCASConflict
+	}
+	return err
+}
+
+// Clear does nothing on MemcachedCache.
+func (c *MemcachedCache) Clear() error {
+	return nil
+}
+
+// expiration converts TTL (milliseconds) into expiration time of memcached.
+// TTL less than a second is rounded up to a second.
+func expiration(ttl int64) int64 {
+	if ttl < 1 {
+		return 0
+	}
+
+	sec := (ttl + 999) / 1000
+	if sec > maxRelativeExpiration {
+		return time.Now().Unix() + sec
+	}
+	return sec
+}
+
+// wrapConnError wraps error from memcached connection with eurekache.ErrTimeout or eurekache.ErrConnection.
+// invalid key and error reply from memcached are returned as it is.
+func wrapConnError(err error) error {
+	var srvErr *serverError
+	switch {
+	case err == errInvalidKey,
+		errors.As(err, &srvErr):
+		return err
+	}
+
+	return eurekache.WrapConnError(err)
+}
@@ -0,0 +1,174 @@
+package memcachedcache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/evalphobia/eurekache"
+)
+
+var testPrefix = "eurekache:"
+
+func TestNewMemcachedCache(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewMemcachedCache("127.0.0.1:11211")
+	assert.Equal("memcached", c.Name())
+	assert.Len(c.client.servers, 1)
+	assert.EqualValues(0, c.defaultTTL)
+
+	c.SetName("mc")
+	c.SetTTL(1000)
+	c.SetPrefix(testPrefix)
+	c.SetTimeout(time.Second)
+	c.SetMaxIdleConns(5)
+	assert.Equal("mc", c.Name())
+	assert.EqualValues(1000, c.defaultTTL)
+	assert.Equal(testPrefix, c.prefix)
+	assert.Equal(time.Second, c.client.timeout)
+	assert.Equal(5, c.client.maxIdle)
+}
+
+func TestMemcachedCache(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewMemcachedCache(testMemcachedAddr(t))
+	defer c.Close()
+	c.SetPrefix(testPrefix)
+	c.SetTTL(10000)
+	assert.NoError(c.Ping(context.Background()))
+
+	assert.NoError(c.Set("key", "value"))
+
+	var v string
+	ok, err := c.Lookup("key", &v)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("value", v)
+
+	item, ok, err := c.LookupItem(context.Background(), "key", &v)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.True(item.ExpiredAt > item.CreatedAt)
+
+	iv, ok := c.GetInterface("key")
+	assert.True(ok)
+	assert.Equal("value", iv)
+
+	b, ok := c.GetGobBytes("key")
+	assert.True(ok)
+	assert.NotEmpty(b)
+
+	// type mismatch
+	var i int
+	ok, err = c.Lookup("key", &i)
+	assert.False(ok)
+	assert.True(errors.Is(err, eurekache.ErrDecode))
+
+	// nil data is deleted
+	assert.NoError(c.SetExpire("key", nil, 0))
+	assert.False(c.Get("key", &v))
+	assert.NoError(c.Clear())
+}
+
+func TestMemcachedCacheGetMulti(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewMemcachedCache(testMemcachedAddr(t), testMemcachedAddr(t))
+	defer c.Close()
+	c.SetPrefix(testPrefix)
+
+	var keys []string
+	for i := 0; i < 20; i++ {
+		key := "keyGetMulti" + strconv.Itoa(i)
+		keys = append(keys, key)
+		assert.NoError(c.SetExpire(key, i, 10000))
+	}
+
+	// keys are spread across the servers
+	for _, srv := range c.client.servers {
+		srv.mu.Lock()
+		assert.NotEmpty(srv.idle)
+		srv.mu.Unlock()
+	}
+
+	values, err := c.GetMulti(context.Background(), append(keys, "keyGetMultiNothing"))
+	assert.NoError(err)
+	assert.Len(values, 20)
+	for i, key := range keys {
+		assert.Equal(i, values[key])
+	}
+}
+
+func TestMemcachedCacheCAS(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewMemcachedCache(testMemcachedAddr(t))
+	defer c.Close()
+	c.SetPrefix(testPrefix)
+	ctx := context.Background()
+
+	// add when the key does not exist
+	var v int
+	cas, ok, err := c.LookupCAS(ctx, "keyCAS", &v)
+	assert.False(ok)
+	assert.NoError(err)
+	assert.Zero(cas)
+	assert.NoError(c.CompareAndSwap("keyCAS", 1, 10000, cas))
+	assert.Equal(ErrCASConflict, c.CompareAndSwap("keyCAS", 1, 10000, cas))
+
+	cas, ok, err = c.LookupCAS(ctx, "keyCAS", &v)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal(1, v)
+
+	// modified by others
+	assert.NoError(c.Set("keyCAS", 100))
+	assert.Equal(ErrCASConflict, c.CompareAndSwap("keyCAS", v+1, 10000, cas))
+
+	cas, _, _ = c.LookupCAS(ctx, "keyCAS", &v)
+	assert.NoError(c.CompareAndSwap("keyCAS", v+1, 10000, cas))
+	assert.True(c.Get("keyCAS", &v))
+	assert.Equal(101, v)
+
+	// deleted by others
+	c.Set("keyCAS", nil)
+	assert.Equal(ErrCASConflict, c.CompareAndSwap("keyCAS", v+1, 10000, cas))
+}
+
+func TestMemcachedCacheError(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewMemcachedCache("127.0.0.1:1")
+	var v string
+	ok, err := c.Lookup("key", &v)
+	assert.False(ok)
+	assert.True(errors.Is(err, eurekache.ErrConnection))
+	assert.True(errors.Is(c.Ping(context.Background()), eurekache.ErrConnection))
+
+	_, err = NewMemcachedCache().Lookup("key", &v)
+	assert.True(errors.Is(err, eurekache.ErrConnection))
+
+	_, err = c.Lookup("invalid key", &v)
+	assert.Equal(errInvalidKey, err)
+}
+
+func TestExpiration(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.EqualValues(0, expiration(0))
+	assert.EqualValues(0, expiration(-1))
+	assert.EqualValues(1, expiration(1))
+	assert.EqualValues(1, expiration(1000))
+	assert.EqualValues(2, expiration(1001))
+	assert.EqualValues(maxRelativeExpiration, expiration(maxRelativeExpiration*1000))
+
+	// unix time
+	exp := expiration((maxRelativeExpiration + 1) * 1000)
+	assert.InDelta(time.Now().Unix()+maxRelativeExpiration+1, exp, 2)
+}
@@ -2,6 +2,7 @@ package memorycache
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"sync"
 	"time"
@@ -9,14 +10,31 @@ import (
 	"github.com/evalphobia/eurekache"
 )
 
+// CopyMode is a flag for copying values to isolate cached data from caller mutation
+type CopyMode int
+
+// copy modes
+const (
+	// CopyOnWrite stores a copy of given value
+	CopyOnWrite CopyMode = 1 << iota
+	// CopyOnRead returns a copy of cached value
+	CopyOnRead
+)
+
 // CacheTTL is a cache source for on-memory cache
 // When item size reaches maxSize, the item is selected by FIFO or TTL and erased.
 type CacheTTL struct {
 	itemsMu     sync.RWMutex
 	items       map[string]*eurekache.Item
+	tags        map[string]map[string]struct{}
+	keyTags     map[string][]string
 	deleteQueue []string
 	maxSize     int
 	defaultTTL  int64
+	jitter      eurekache.Jitter
+	copyMode    CopyMode
+	staleGrace  int64
+	name        string
 }
 
 // NewCacheTTL returns initialized CacheTTL
@@ -28,31 +46,118 @@ func NewCacheTTL(max int) *CacheTTL {
 
 	return &CacheTTL{
 		items:       make(map[string]*eurekache.Item),
+		tags:        make(map[string]map[string]struct{}),
+		keyTags:     make(map[string][]string),
 		maxSize:     max,
 		deleteQueue: make([]string, 0, max),
+		name:        "memory",
 	}
 }
 
+// SetName sets the name of the cache source
+func (c *CacheTTL) SetName(name string) {
+	c.name = name
+}
+
+// Name returns the name of the cache source
+func (c *CacheTTL) Name() string {
+	return c.name
+}
+
+// Ping returns nil because on-memory cache is always reachable, unless ctx is done.
+func (c *CacheTTL) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
 // SetTTL sets default TTL (milliseconds)
 func (c *CacheTTL) SetTTL(ttl int64) {
 	c.defaultTTL = ttl
 }
 
+// SetJitter sets jitter for TTL
+func (c *CacheTTL) SetJitter(j eurekache.Jitter) {
+	c.jitter = j
+}
+
+// SetStaleGrace sets grace period (millisec) to keep expired data for LookupStale.
+// Expired data is never returned by Get or Lookup.
+func (c *CacheTTL) SetStaleGrace(grace int64) {
+	c.staleGrace = grace
+}
+
+// SetCopyMode sets copy mode for isolating cached data.
+// Values are copied by eurekache.Clone.
+func (c *CacheTTL) SetCopyMode(mode CopyMode) {
+	c.copyMode = mode
+}
+
 // Get searches cache on memory by given key and returns flag of cache is existed or not.
 // when cache hit, data is assigned.
 func (c *CacheTTL) Get(key string, data interface{}) bool {
+	ok, _ := c.Lookup(key, data)
+	return ok
+}
+
+// Lookup searches cache on memory by given key and returns flag of cache is existed or not, and error.
+// when cache hit, data is assigned.
+func (c *CacheTTL) Lookup(key string, data interface{}) (bool, error) {
+	_, ok, err := c.LookupItem(context.Background(), key, data)
+	return ok, err
+}
+
+// LookupItem searches cache on memory by given key and returns Item and error.
+// when cache hit, data is assigned.
+func (c *CacheTTL) LookupItem(ctx context.Context, key string, data interface{}) (*eurekache.Item, bool, error) {
 	c.itemsMu.RLock()
 	defer c.itemsMu.RUnlock()
 
 	item, ok := c.items[key]
 	switch {
 	case !ok:
-		return false
+		return nil, false, nil
 	case !c.isValidItem(item):
-		return false
-	default:
-		return eurekache.CopyValue(data, item.Value)
+		return nil, false, nil
+	}
+
+	v, err := c.readValue(item)
+	if err != nil {
+		return nil, false, eurekache.WrapError(eurekache.ErrDecode, err)
+	}
+
+	err = eurekache.CopyValueWithError(data, v)
+	if err != nil {
+		return nil, false, err
+	}
+	return &eurekache.Item{
+		CreatedAt: item.CreatedAt,
+		ExpiredAt: item.ExpiredAt,
+		Value:     v,
+	}, true, nil
+}
+
+// LookupStale searches cache on memory by given key including expired data in the grace period.
+func (c *CacheTTL) LookupStale(ctx context.Context, key string, data interface{}) (bool, error) {
+	c.itemsMu.RLock()
+	defer c.itemsMu.RUnlock()
+
+	item, ok := c.items[key]
+	switch {
+	case !ok:
+		return false, nil
+	case !c.isStaleItem(item):
+		return false, nil
+	}
+
+	v, err := c.readValue(item)
+	if err != nil {
+		return false, eurekache.WrapError(eurekache.ErrDecode, err)
+	}
+
+	err = eurekache.CopyValueWithError(data, v)
+	if err != nil {
+		return false, err
 	}
+	return true, nil
 }
 
 // GetInterface searches cache on memory by given key and returns interface value.
@@ -62,7 +167,10 @@ func (c *CacheTTL) GetInterface(key string) (interface{}, bool) {
 
 	if item, ok := c.items[key]; ok {
 		if c.isValidItem(item) {
-			return item.Value, true
+			v, err := c.readValue(item)
+			if err == nil {
+				return v, true
+			}
 		}
 	}
 
@@ -95,13 +203,28 @@ func (c *CacheTTL) Set(key string, data interface{}) error {
 
 // SetExpire sets data with TTL.
 func (c *CacheTTL) SetExpire(key string, data interface{}, ttl int64) error {
+	return c.SetWithTags(key, data, ttl)
+}
+
+// SetWithTags sets data with TTL and tags.
+// The data is deleted when one of the tags is invalidated.
+func (c *CacheTTL) SetWithTags(key string, data interface{}, ttl int64, tags ...string) error {
 	if key == "" {
 		return nil
 	}
 
+	if data != nil && c.copyMode&CopyOnWrite != 0 {
+		v, err := eurekache.Clone(data)
+		if err != nil {
+			return err
+		}
+		data = v
+	}
+
 	c.itemsMu.Lock()
 	defer c.itemsMu.Unlock()
 
+	c.removeTags(key)
 	if data == nil {
 		delete(c.items, key)
 		return nil
@@ -113,10 +236,23 @@ func (c *CacheTTL) SetExpire(key string, data interface{}, ttl int64) error {
 	}
 
 	item := eurekache.NewItem()
-	item.SetExpire(ttl)
+	item.SetExpire(c.jitter.Apply(ttl))
 	item.Value = data
 	c.items[key] = item
 	c.deleteQueue = append(c.deleteQueue, key)
+	c.addTags(key, tags)
+	return nil
+}
+
+// InvalidateTag deletes all of cached data related to the tag.
+func (c *CacheTTL) InvalidateTag(tag string) error {
+	c.itemsMu.Lock()
+	defer c.itemsMu.Unlock()
+
+	for key := range c.tags[tag] {
+		c.removeTags(key)
+		delete(c.items, key)
+	}
 	return nil
 }
 
@@ -125,10 +261,41 @@ func (c *CacheTTL) Clear() error {
 	c.itemsMu.Lock()
 	defer c.itemsMu.Unlock()
 	c.items = make(map[string]*eurekache.Item)
+	c.tags = make(map[string]map[string]struct{})
+	c.keyTags = make(map[string][]string)
 	c.deleteQueue = make([]string, 0, c.maxSize)
 	return nil
 }
 
+// addTags adds the key into tag index
+func (c *CacheTTL) addTags(key string, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	c.keyTags[key] = tags
+}
+
+// removeTags removes the key from tag index
+func (c *CacheTTL) removeTags(key string) {
+	for _, tag := range c.keyTags[key] {
+		keys := c.tags[tag]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+	delete(c.keyTags, key)
+}
+
 func (c *CacheTTL) deleteOldest() {
 	if len(c.deleteQueue) == 0 {
 		return
@@ -143,9 +310,18 @@ func (c *CacheTTL) deleteOldest() {
 		return
 	}
 
+	c.removeTags(oldestKey)
 	delete(c.items, oldestKey)
 }
 
+// readValue returns value of the item, or its copy on CopyOnRead mode
+func (c *CacheTTL) readValue(item *eurekache.Item) (interface{}, error) {
+	if c.copyMode&CopyOnRead == 0 {
+		return item.Value, nil
+	}
+	return eurekache.Clone(item.Value)
+}
+
 // isValidItem checks if the item is expired or not
 func (c *CacheTTL) isValidItem(item *eurekache.Item) bool {
 	if item.Value == nil {
@@ -153,3 +329,12 @@ func (c *CacheTTL) isValidItem(item *eurekache.Item) bool {
 	}
 	return item.ExpiredAt > time.Now().UnixNano()
 }
+
+// isStaleItem checks if the item is not expired or in the grace period
+func (c *CacheTTL) isStaleItem(item *eurekache.Item) bool {
+	if item.Value == nil {
+		return false
+	}
+	grace := c.staleGrace * int64(time.Millisecond)
+	return item.ExpiredAt > time.Now().UnixNano()-grace
+}
@@ -2,7 +2,9 @@ package memorycache
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -53,6 +55,120 @@ func TestGet(t *testing.T) {
 	assert.False(ok)
 }
 
+func TestLookup(t *testing.T) {
+	assert := assert.New(t)
+	m := NewCacheTTL(10)
+	m.Set("key", "the value")
+
+	var result string
+	var ok bool
+	var err error
+
+	// miss cache
+	ok, err = m.Lookup("nokey", &result)
+	assert.False(ok)
+	assert.NoError(err)
+
+	// hit cache
+	ok, err = m.Lookup("key", &result)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("the value", result)
+
+	// type mismatch
+	var resultInt int
+	ok, err = m.Lookup("key", &resultInt)
+	assert.False(ok)
+	assert.True(errors.Is(err, eurekache.ErrTypeMismatch))
+
+	// copy failure
+	m.Set("func", func() {})
+	m.SetCopyMode(CopyOnRead)
+	var resultFunc func()
+	ok, err = m.Lookup("func", &resultFunc)
+	assert.False(ok)
+	assert.True(errors.Is(err, eurekache.ErrDecode))
+}
+
+func TestLookupItem(t *testing.T) {
+	assert := assert.New(t)
+	m := NewCacheTTL(10)
+	m.SetExpire("key", "the value", 2000)
+
+	var result string
+
+	// miss cache
+	item, ok, err := m.LookupItem(context.Background(), "nokey", &result)
+	assert.False(ok)
+	assert.NoError(err)
+	assert.Nil(item)
+
+	// hit cache
+	item, ok, err = m.LookupItem(context.Background(), "key", &result)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("the value", result)
+	assert.Equal(m.items["key"].CreatedAt, item.CreatedAt)
+	assert.Equal(m.items["key"].ExpiredAt, item.ExpiredAt)
+}
+
+func TestLookupStale(t *testing.T) {
+	assert := assert.New(t)
+	m := NewCacheTTL(10)
+	m.SetExpire("key", "the value", 10)
+	time.Sleep(20 * time.Millisecond)
+
+	var result string
+
+	// expired data is not returned without grace
+	ok, err := m.LookupStale(context.Background(), "key", &result)
+	assert.False(ok)
+	assert.NoError(err)
+
+	// in grace period
+	m.SetStaleGrace(1000)
+	ok, err = m.LookupStale(context.Background(), "key", &result)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("the value", result)
+	assert.False(m.Get("key", &result), "Get does not return expired data")
+
+	// over grace period
+	m.SetStaleGrace(5)
+	ok, _ = m.LookupStale(context.Background(), "key", &result)
+	assert.False(ok)
+
+	// not expired
+	m.SetExpire("key2", "the value2", 0)
+	ok, _ = m.LookupStale(context.Background(), "key2", &result)
+	assert.True(ok)
+	assert.Equal("the value2", result)
+
+	// miss cache
+	ok, err = m.LookupStale(context.Background(), "nokey", &result)
+	assert.False(ok)
+	assert.NoError(err)
+}
+
+func TestName(t *testing.T) {
+	assert := assert.New(t)
+	m := NewCacheTTL(1)
+	assert.Equal("memory", m.Name())
+
+	m.SetName("local")
+	assert.Equal("local", m.Name())
+}
+
+func TestPing(t *testing.T) {
+	assert := assert.New(t)
+	m := NewCacheTTL(1)
+	assert.NoError(m.Ping(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(context.Canceled, m.Ping(ctx))
+}
+
 func TestGetInterface(t *testing.T) {
 	assert := assert.New(t)
 	m := NewCacheTTL(1)
@@ -168,6 +284,123 @@ func TestSetExpire(t *testing.T) {
 	assert.EqualValues(expected, item.ExpiredAt)
 }
 
+func TestSetExpireWithJitter(t *testing.T) {
+	assert := assert.New(t)
+	m := NewCacheTTL(1)
+	m.SetJitter(eurekache.Jitter{Range: 1000})
+
+	m.SetExpire("key", "the value", 2000)
+
+	item, ok := m.items["key"]
+	assert.True(ok)
+
+	min := item.CreatedAt + 2000*int64(time.Millisecond)
+	max := item.CreatedAt + 3000*int64(time.Millisecond)
+	assert.True(min <= item.ExpiredAt)
+	assert.True(item.ExpiredAt <= max)
+}
+
+func TestSetWithTags(t *testing.T) {
+	assert := assert.New(t)
+	m := NewCacheTTL(2)
+
+	m.SetWithTags("key1", "value1", 0, "tag1", "tag2")
+	m.SetWithTags("key2", "value2", 0, "tag2")
+	assert.Len(m.items, 2)
+	assert.Len(m.tags["tag1"], 1)
+	assert.Len(m.tags["tag2"], 2)
+	assert.Equal([]string{"tag1", "tag2"}, m.keyTags["key1"])
+
+	// overwrite without tags
+	m.Set("key1", "value1")
+	assert.Len(m.items, 2)
+	assert.NotContains(m.tags, "tag1")
+	assert.Len(m.tags["tag2"], 1)
+	assert.NotContains(m.keyTags, "key1")
+
+	// evict oldest
+	m.Set("key3", "value3")
+	m.Set("key4", "value4")
+	assert.NotContains(m.items, "key2")
+	assert.NotContains(m.tags, "tag2")
+	assert.NotContains(m.keyTags, "key2")
+}
+
+func TestInvalidateTag(t *testing.T) {
+	assert := assert.New(t)
+	m := NewCacheTTL(10)
+
+	m.SetWithTags("key1", "value1", 0, "tag1", "tag2")
+	m.SetWithTags("key2", "value2", 0, "tag2")
+	m.SetWithTags("key3", "value3", 0, "tag3")
+	m.Set("key4", "value4")
+
+	var result string
+
+	err := m.InvalidateTag("tag1")
+	assert.NoError(err)
+	assert.False(m.Get("key1", &result))
+	assert.True(m.Get("key2", &result))
+	assert.Len(m.tags["tag2"], 1)
+
+	err = m.InvalidateTag("tag2")
+	assert.NoError(err)
+	assert.False(m.Get("key2", &result))
+	assert.True(m.Get("key3", &result))
+	assert.True(m.Get("key4", &result))
+	assert.NotContains(m.tags, "tag2")
+
+	// unknown tag
+	err = m.InvalidateTag("tag_unknown")
+	assert.NoError(err)
+	assert.Len(m.items, 2)
+}
+
+func TestCopyMode(t *testing.T) {
+	assert := assert.New(t)
+
+	// no copy
+	m := NewCacheTTL(10)
+	data := map[string]int{"a": 1}
+	m.Set("key", data)
+	data["a"] = 2
+	v, ok := m.GetInterface("key")
+	assert.True(ok)
+	assert.Equal(2, v.(map[string]int)["a"])
+
+	// copy on write
+	m = NewCacheTTL(10)
+	m.SetCopyMode(CopyOnWrite)
+	data = map[string]int{"a": 1}
+	m.Set("key", data)
+	data["a"] = 2
+	v, ok = m.GetInterface("key")
+	assert.True(ok)
+	assert.Equal(1, v.(map[string]int)["a"])
+
+	err := m.Set("func", func() {})
+	assert.Error(err)
+	assert.NotContains(m.items, "func")
+
+	// copy on read
+	m = NewCacheTTL(10)
+	m.SetCopyMode(CopyOnRead)
+	m.Set("key", map[string]int{"a": 1})
+	v, ok = m.GetInterface("key")
+	assert.True(ok)
+	v.(map[string]int)["a"] = 2
+
+	var result map[string]int
+	ok = m.Get("key", &result)
+	assert.True(ok)
+	assert.Equal(1, result["a"])
+	result["a"] = 3
+
+	v, ok = m.GetInterface("key")
+	assert.True(ok)
+	assert.Equal(1, v.(map[string]int)["a"])
+}
+
 func TestDeleteOldest(t *testing.T) {
 	assert := assert.New(t)
 	m := NewCacheTTL(4)
@@ -224,6 +457,13 @@ func TestClear(t *testing.T) {
 	assert.Len(m.items, 0)
 	assert.Len(m.deleteQueue, 0)
 
+	// clear tags
+	m.SetWithTags("key1", "value1", 0, "tag1")
+	err = m.Clear()
+	assert.NoError(err)
+	assert.Len(m.tags, 0)
+	assert.Len(m.keyTags, 0)
+
 	// set again
 	m.Set("key1", "value1")
 	m.Set("key2", "value2")
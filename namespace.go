@@ -0,0 +1,192 @@
+package eurekache
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	namespaceKeyPrefix   = "__ns:"
+	defaultGenerationTTL = time.Second
+)
+
+var errNoCacheSource = errors.New("eurekache: no cache source")
+
+// Namespace is a scoped view of Eurekache.
+// Keys are prefixed with namespace name and generation number,
+// and all of data in the namespace is invalidated by changing the generation.
+// The generation is stored only in the last cache source, which is usually shared by instances (e.g. redis behind memory cache),
+// so the invalidation is seen by other instances after the generation TTL.
+type Namespace struct {
+	cache         *Eurekache
+	name          string
+	generationTTL time.Duration
+
+	mu        sync.Mutex
+	gen       int64
+	checkedAt time.Time
+}
+
+// Namespace returns Namespace for given name
+func (e *Eurekache) Namespace(name string) *Namespace {
+	return &Namespace{
+		cache:         e,
+		name:          name,
+		generationTTL: defaultGenerationTTL,
+	}
+}
+
+// SetGenerationTTL sets the time to reuse the generation without reading the cache source. (default: 1s)
+// Invalidate on other instances is seen after it, and 0 reads the generation every time.
+func (n *Namespace) SetGenerationTTL(d time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.generationTTL = d
+}
+
+// Name returns namespace name
+func (n *Namespace) Name() string {
+	return n.name
+}
+
+// Get searches cache in the namespace by given key and returns flag of cache is existed or not.
+// when cache hit, data is assigned.
+func (n *Namespace) Get(key string, data interface{}) bool {
+	k, err := n.key(key)
+	if err != nil {
+		return false
+	}
+	return n.cache.Get(k, data)
+}
+
+// GetInterface searches cache in the namespace by given key and returns interface value.
+func (n *Namespace) GetInterface(key string) (interface{}, bool) {
+	k, err := n.key(key)
+	if err != nil {
+		return nil, false
+	}
+	return n.cache.GetInterface(k)
+}
+
+// GetGobBytes searches cache in the namespace by given key and returns gob-encoded value.
+func (n *Namespace) GetGobBytes(key string) ([]byte, bool) {
+	k, err := n.key(key)
+	if err != nil {
+		return nil, false
+	}
+	return n.cache.GetGobBytes(k)
+}
+
+// Set sets data in the namespace into all of cache sources.
+func (n *Namespace) Set(key string, data interface{}) error {
+	k, err := n.key(key)
+	if err != nil {
+		return err
+	}
+	return n.cache.Set(k, data)
+}
+
+// SetExpire sets data in the namespace with TTL.
+func (n *Namespace) SetExpire(key string, data interface{}, ttl int64) error {
+	k, err := n.key(key)
+	if err != nil {
+		return err
+	}
+	return n.cache.SetExpire(k, data, ttl)
+}
+
+// SetWithTags sets data in the namespace with TTL and tags.
+func (n *Namespace) SetWithTags(key string, data interface{}, ttl int64, tags ...string) error {
+	k, err := n.key(key)
+	if err != nil {
+		return err
+	}
+	return n.cache.SetWithTags(k, data, ttl, tags...)
+}
+
+// Invalidate discards all of cached data in the namespace by changing the generation.
+// Old data is not deleted and remains in cache sources until expired or evicted.
+func (n *Namespace) Invalidate() error {
+	return n.setGeneration(newGeneration())
+}
+
+// key returns actual key name with namespace and generation
+func (n *Namespace) key(key string) (string, error) {
+	gen, err := n.generation()
+	if err != nil {
+		return "", err
+	}
+	return namespaceKeyPrefix + n.name + ":" + strconv.FormatInt(gen, 10) + ":" + key, nil
+}
+
+// generation returns current generation of the namespace stored in the last cache source.
+// New generation is created only when it's genuinely missing, and error of cache sources is returned
+// to avoid discarding the namespace by a temporary failure.
+func (n *Namespace) generation() (int64, error) {
+	n.mu.Lock()
+	gen, fresh := n.gen, time.Since(n.checkedAt) < n.generationTTL
+	n.mu.Unlock()
+	if gen != 0 && fresh {
+		return gen, nil
+	}
+
+	c := n.generationSource()
+	if c == nil {
+		return 0, errNoCacheSource
+	}
+	ok, err := lookup(c, n.generationKey(), &gen)
+	switch {
+	case ok:
+		n.remember(gen)
+		return gen, nil
+	case err != nil:
+		return 0, err
+	}
+
+	gen = newGeneration()
+	return gen, n.setGeneration(gen)
+}
+
+// setGeneration stores the generation into the last cache source without TTL
+func (n *Namespace) setGeneration(gen int64) error {
+	c := n.generationSource()
+	if c == nil {
+		return errNoCacheSource
+	}
+	err := c.SetExpire(n.generationKey(), gen, 0)
+	if err != nil {
+		return err
+	}
+	n.remember(gen)
+	return nil
+}
+
+// remember keeps the generation until the generation TTL
+func (n *Namespace) remember(gen int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.gen = gen
+	n.checkedAt = time.Now()
+}
+
+// generationSource returns the last cache source storing the generation
+func (n *Namespace) generationSource() Cache {
+	caches := n.cache.caches
+	if len(caches) == 0 {
+		return nil
+	}
+	return caches[len(caches)-1]
+}
+
+// generationKey returns key name for the generation of the namespace
+func (n *Namespace) generationKey() string {
+	return namespaceKeyPrefix + n.name
+}
+
+// newGeneration returns new generation number.
+// unix nanosec is used to avoid reusing old generation after the number is evicted.
+func newGeneration() int64 {
+	return time.Now().UnixNano()
+}
@@ -0,0 +1,176 @@
+package eurekache_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/evalphobia/eurekache"
+	"github.com/evalphobia/eurekache/memorycache"
+)
+
+func TestNamespace(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	m := memorycache.NewCacheTTL(10)
+	e.SetCacheSources([]Cache{m})
+
+	ns1 := e.Namespace("ns1")
+	ns2 := e.Namespace("ns2")
+	assert.Equal("ns1", ns1.Name())
+
+	ns1.Set("key", "value1")
+	ns2.SetExpire("key", "value2", 10000)
+	e.Set("key", "value")
+
+	var result string
+	var ok bool
+
+	ok = ns1.Get("key", &result)
+	assert.True(ok)
+	assert.Equal("value1", result)
+
+	ok = ns2.Get("key", &result)
+	assert.True(ok)
+	assert.Equal("value2", result)
+
+	ok = e.Get("key", &result)
+	assert.True(ok)
+	assert.Equal("value", result)
+
+	v, ok := ns1.GetInterface("key")
+	assert.True(ok)
+	assert.Equal("value1", v)
+
+	b, ok := ns1.GetGobBytes("key")
+	assert.True(ok)
+	assert.NotEmpty(b)
+
+	// same namespace shares the generation
+	ok = e.Namespace("ns1").Get("key", &result)
+	assert.True(ok)
+	assert.Equal("value1", result)
+}
+
+func TestNamespaceInvalidate(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	m := memorycache.NewCacheTTL(10)
+	e.SetCacheSources([]Cache{m})
+
+	ns1 := e.Namespace("ns1")
+	ns2 := e.Namespace("ns2")
+	ns1.Set("key", "value1")
+	ns2.Set("key", "value2")
+
+	var result string
+	var ok bool
+
+	ns1.Invalidate()
+	ok = ns1.Get("key", &result)
+	assert.False(ok)
+
+	ok = ns2.Get("key", &result)
+	assert.True(ok)
+	assert.Equal("value2", result)
+
+	// set after invalidation
+	ns1.Set("key", "value3")
+	ok = ns1.Get("key", &result)
+	assert.True(ok)
+	assert.Equal("value3", result)
+}
+
+func TestNamespaceTags(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	m := memorycache.NewCacheTTL(10)
+	e.SetCacheSources([]Cache{m})
+
+	ns := e.Namespace("ns")
+	ns.SetWithTags("key", "value", 0, "tag")
+
+	var result string
+	assert.True(ns.Get("key", &result))
+
+	err := e.InvalidateTag("tag")
+	assert.NoError(err)
+	assert.False(ns.Get("key", &result))
+}
+
+func TestNamespaceSharedGeneration(t *testing.T) {
+	assert := assert.New(t)
+
+	// instances having own memory cache in front of the shared cache source
+	shared := memorycache.NewCacheTTL(10)
+	e1 := New()
+	e1.SetCacheSources([]Cache{memorycache.NewCacheTTL(10), shared})
+	e2 := New()
+	e2.SetCacheSources([]Cache{memorycache.NewCacheTTL(10), shared})
+
+	ns1 := e1.Namespace("ns")
+	ns2 := e2.Namespace("ns")
+	ns2.SetGenerationTTL(20 * time.Millisecond)
+	assert.NoError(ns1.Set("key", "value"))
+
+	var result string
+	assert.True(ns2.Get("key", &result))
+	assert.Equal("value", result)
+
+	// invalidation on another instance is seen after the generation TTL
+	assert.NoError(ns1.Invalidate())
+	assert.False(ns1.Get("key", &result))
+	assert.Eventually(func() bool {
+		return !ns2.Get("key", &result)
+	}, time.Second, 5*time.Millisecond)
+
+	// no cache source
+	assert.Error(New().Namespace("ns").Set("key", "value"))
+}
+
+func TestNamespaceGenerationError(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	m := memorycache.NewCacheTTL(10)
+	f := &namespaceTestFailCache{CacheTTL: m}
+	e.SetCacheSources([]Cache{f})
+
+	ns := e.Namespace("ns")
+	ns.SetGenerationTTL(0)
+	ns.Set("key", "value")
+
+	// failure of cache source does not create new generation
+	f.err = ErrConnection
+	var result string
+	assert.False(ns.Get("key", &result))
+	assert.True(errors.Is(ns.Set("key", "value2"), ErrConnection))
+
+	f.err = nil
+	assert.True(ns.Get("key", &result))
+	assert.Equal("value", result)
+}
+
+type namespaceTestFailCache struct {
+	*memorycache.CacheTTL
+	err error
+}
+
+func (c *namespaceTestFailCache) Lookup(key string, data interface{}) (bool, error) {
+	if c.err != nil {
+		return false, c.err
+	}
+	return c.CacheTTL.Get(key, data), nil
+}
+
+func (c *namespaceTestFailCache) Set(key string, data interface{}) error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.CacheTTL.Set(key, data)
+}
@@ -0,0 +1,37 @@
+package eurekache
+
+// Notifier is interface for notifying changes of cached data to other instances
+type Notifier interface {
+	NotifyDelete(string) error
+	NotifyClear() error
+	NotifyInvalidateTag(string) error
+}
+
+// SetNotifier sets notifier called after data is changed
+func (e *Eurekache) SetNotifier(n Notifier) {
+	e.notifier = n
+}
+
+// notifyDelete notifies the key is changed
+func (e *Eurekache) notifyDelete(key string) {
+	if e.notifier == nil {
+		return
+	}
+	e.notifier.NotifyDelete(key)
+}
+
+// notifyClear notifies all of data is deleted
+func (e *Eurekache) notifyClear() {
+	if e.notifier == nil {
+		return
+	}
+	e.notifier.NotifyClear()
+}
+
+// notifyInvalidateTag notifies the tag is invalidated
+func (e *Eurekache) notifyInvalidateTag(tag string) {
+	if e.notifier == nil {
+		return
+	}
+	e.notifier.NotifyInvalidateTag(tag)
+}
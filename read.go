@@ -0,0 +1,195 @@
+package eurekache
+
+import (
+	"context"
+	"time"
+)
+
+// ReadStrategy is a strategy for reading data from cache sources
+type ReadStrategy int
+
+// read strategies
+const (
+	// ReadSequential reads data from cache sources one by one in index order
+	ReadSequential ReadStrategy = iota
+	// ReadHedged reads data from the next cache source when the current one
+	// does not respond within the hedge delay or misses
+	ReadHedged
+	// ReadParallel reads data from all of cache sources at the same time
+	ReadParallel
+)
+
+// SetReadStrategy sets strategy for reading data from cache sources.
+// On ReadHedged and ReadParallel, the hit from the highest-priority (lowest index) cache source is used,
+// and the rest of sources are cancelled through the context.
+// The hit from lower-priority source is used without waiting for higher-priority sources
+// that don't respond within the hedge delay. (ReadParallel waits for them when the delay is zero)
+func (e *Eurekache) SetReadStrategy(s ReadStrategy) {
+	e.readStrategy = s
+}
+
+// SetHedgeDelay sets waiting time before reading from the next cache source on ReadHedged
+func (e *Eurekache) SetHedgeDelay(d time.Duration) {
+	e.hedgeDelay = d
+}
+
+// readResult is a result of reading data from a cache source
+type readResult struct {
+	index int
+	hit   bool
+	err   error
+}
+
+// readSources runs fn for each cache sources by the read strategy,
+// and returns the index of the highest-priority hit or -1 with the first error.
+// fn may be called concurrently, and it must write into the memory for the index only.
+func (e *Eurekache) readSources(ctx context.Context, fn func(context.Context, int, Cache) (bool, error)) (int, error) {
+	caches := e.caches
+	switch e.readStrategy {
+	case ReadHedged:
+		return readHedged(ctx, caches, e.hedgeDelay, e.hedgeDelay, fn)
+	case ReadParallel:
+		return readHedged(ctx, caches, 0, e.hedgeDelay, fn)
+	}
+
+	var firstErr error
+	for i, c := range caches {
+		if ctx.Err() != nil {
+			return -1, contextError(ctx)
+		}
+
+		ok, err := fn(ctx, i, c)
+		if ok {
+			return i, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return -1, firstErr
+}
+
+// readHedged starts reading from the next cache source after the delay or when the previous one misses.
+// all of cache sources are started at once when delay is zero.
+// The hit is used when higher-priority sources missed or they have been running longer than grace.
+func readHedged(ctx context.Context, caches []Cache, delay, grace time.Duration, fn func(context.Context, int, Cache) (bool, error)) (int, error) {
+	if len(caches) == 0 {
+		return -1, nil
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan readResult, len(caches))
+	results := make([]*readResult, len(caches))
+	startedAt := make([]time.Time, len(caches))
+	started := 0
+	hit := false
+	start := func() {
+		if started >= len(caches) || hit {
+			return
+		}
+		i := started
+		started++
+		startedAt[i] = time.Now()
+		go func() {
+			ok, err := fn(ctx, i, caches[i])
+			ch <- readResult{index: i, hit: ok, err: err}
+		}()
+	}
+
+	start()
+	if delay <= 0 {
+		for started < len(caches) {
+			start()
+		}
+	}
+
+	var tick <-chan time.Time
+	if delay > 0 {
+		ticker := time.NewTicker(delay)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// wakes up when higher-priority sources run out of grace
+	wake := time.NewTimer(time.Hour)
+	defer wake.Stop()
+
+	for {
+		select {
+		case r := <-ch:
+			results[r.index] = &r
+			if r.hit {
+				hit = true
+			} else {
+				start()
+			}
+		case <-tick:
+			start()
+		case <-wake.C:
+		case <-ctx.Done():
+			return -1, contextError(parent)
+		}
+
+		i, ok, wait := pickHit(results, startedAt, grace)
+		switch {
+		case ok && i < 0:
+			return -1, firstReadError(results)
+		case ok:
+			return i, nil
+		case wait > 0:
+			if !wake.Stop() {
+				select {
+				case <-wake.C:
+				default:
+				}
+			}
+			wake.Reset(wait)
+		}
+	}
+}
+
+// pickHit returns the index of the hit when all of higher-priority sources missed or ran out of grace.
+// -1 is returned when all of sources missed, and false is returned when the result is not decided yet
+// with the waiting time until higher-priority sources run out of grace.
+func pickHit(results []*readResult, startedAt []time.Time, grace time.Duration) (int, bool, time.Duration) {
+	now := time.Now()
+	var wait time.Duration
+	waiting := false // higher-priority source is running without grace
+	for i, r := range results {
+		switch {
+		case r == nil:
+			if grace <= 0 || startedAt[i].IsZero() {
+				waiting = true
+				continue
+			}
+			if d := startedAt[i].Add(grace).Sub(now); d > wait {
+				wait = d
+			}
+		case r.hit:
+			if waiting || wait > 0 {
+				return -1, false, wait
+			}
+			return i, true, 0
+		}
+	}
+
+	for _, r := range results {
+		if r == nil {
+			return -1, false, 0
+		}
+	}
+	return -1, true, 0
+}
+
+// firstReadError returns the first error of the results in index order
+func firstReadError(results []*readResult) error {
+	for _, r := range results {
+		if r != nil && r.err != nil {
+			return r.err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package eurekache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetReadStrategy(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	assert.Equal(ReadSequential, e.readStrategy)
+
+	e.SetReadStrategy(ReadHedged)
+	e.SetHedgeDelay(10 * time.Millisecond)
+	assert.Equal(ReadHedged, e.readStrategy)
+	assert.Equal(10*time.Millisecond, e.hedgeDelay)
+}
+
+func TestReadHedged(t *testing.T) {
+	assert := assert.New(t)
+
+	c1 := &dummyHedgeCache{sleep: 100 * time.Millisecond}
+	c2 := &dummyHedgeCache{sleep: 10 * time.Millisecond, value: "value2"}
+	c3 := &dummyHedgeCache{value: "value3"}
+
+	e := New()
+	e.SetCacheSources([]Cache{c1, c2, c3})
+	e.SetReadStrategy(ReadHedged)
+	e.SetHedgeDelay(20 * time.Millisecond)
+
+	// c2 is started after the delay, and c3 is not started after c2 hit
+	var v string
+	start := time.Now()
+	assert.True(e.Get("key", &v))
+	assert.Equal("value2", v)
+	assert.True(time.Since(start) < 100*time.Millisecond, "does not wait for the slow higher-priority source")
+	assert.EqualValues(1, c2.count())
+	assert.EqualValues(0, c3.count())
+	assert.Eventually(func() bool { return c1.cancelCount() == 1 }, time.Second, time.Millisecond)
+
+	// the higher-priority hit within the delay is used
+	c1.sleep = 5 * time.Millisecond
+	c1.value = "value1"
+	c2.sleep = 0
+	assert.True(e.Get("key", &v))
+	assert.Equal("value1", v)
+	assert.EqualValues(1, c2.count())
+
+	// the next source is started immediately on miss
+	c1.sleep = 0
+	c1.value = ""
+	c2.sleep = 5 * time.Millisecond
+	start = time.Now()
+	assert.True(e.Get("key", &v))
+	assert.Equal("value2", v)
+	assert.True(time.Since(start) < 50*time.Millisecond)
+	assert.EqualValues(0, c3.count())
+}
+
+func TestReadParallel(t *testing.T) {
+	assert := assert.New(t)
+
+	c1 := &dummyHedgeCache{sleep: 10 * time.Millisecond, value: "value1"}
+	c2 := &dummyHedgeCache{sleep: 500 * time.Millisecond, value: "value2"}
+	c3 := &dummyHedgeCache{value: "value3"}
+
+	e := New()
+	e.SetCacheSources([]Cache{c1, c2, c3})
+	e.SetReadStrategy(ReadParallel)
+
+	// the highest-priority hit is used and the rest are cancelled
+	var v string
+	start := time.Now()
+	assert.True(e.Get("key", &v))
+	assert.Equal("value1", v)
+	assert.True(time.Since(start) < 200*time.Millisecond)
+	assert.EqualValues(1, c3.count())
+	assert.Eventually(func() bool { return c2.cancelCount() == 1 }, time.Second, time.Millisecond)
+
+	iv, ok := e.GetInterface("key")
+	assert.True(ok)
+	assert.Equal("value1", iv)
+
+	b, ok := e.GetGobBytes("key")
+	assert.True(ok)
+	assert.Equal([]byte("value1"), b)
+}
+
+func TestReadHungPrimary(t *testing.T) {
+	assert := assert.New(t)
+
+	c1 := &dummyHedgeCache{sleep: time.Hour, value: "value1"}
+	c2 := &dummyHedgeCache{sleep: 10 * time.Millisecond, value: "value2"}
+
+	e := New()
+	e.SetCacheSources([]Cache{c1, c2})
+	e.SetHedgeDelay(50 * time.Millisecond)
+
+	for _, s := range []ReadStrategy{ReadHedged, ReadParallel} {
+		e.SetReadStrategy(s)
+
+		var v string
+		start := time.Now()
+		assert.True(e.Get("key", &v))
+		assert.Equal("value2", v)
+		assert.True(time.Since(start) < 200*time.Millisecond)
+	}
+	assert.Eventually(func() bool { return c1.cancelCount() == 2 }, time.Second, time.Millisecond)
+}
+
+func TestReadParallelMiss(t *testing.T) {
+	assert := assert.New(t)
+
+	errSource := errors.New("source error")
+	c1 := &dummyHedgeCache{err: errSource}
+	c2 := &dummyHedgeCache{}
+
+	e := New()
+	e.SetCacheSources([]Cache{c1, c2})
+	e.SetReadStrategy(ReadParallel)
+
+	var v string
+	ok, err := e.Lookup("key", &v)
+	assert.False(ok)
+	assert.Equal(errSource, err)
+
+	// timeout
+	c2.sleep = 100 * time.Millisecond
+	c2.value = "value"
+	e.SetReadTimeout(10 * time.Millisecond)
+	ok, err = e.Lookup("key", &v)
+	assert.False(ok)
+	assert.Equal(ErrTimeout, err)
+	assert.Equal("", v)
+
+	// no cache source
+	e.SetCacheSources(nil)
+	ok, err = e.Lookup("key", &v)
+	assert.False(ok)
+	assert.NoError(err)
+}
+
+type dummyHedgeCache struct {
+	dummyCache
+	sleep     time.Duration
+	value     string
+	err       error
+	called    int32
+	cancelled int32
+}
+
+func (d *dummyHedgeCache) LookupContext(ctx context.Context, k string, v interface{}) (bool, error) {
+	atomic.AddInt32(&d.called, 1)
+	select {
+	case <-time.After(d.sleep):
+	case <-ctx.Done():
+		atomic.AddInt32(&d.cancelled, 1)
+		return false, ctx.Err()
+	}
+
+	if d.value == "" {
+		return false, d.err
+	}
+	return CopyValue(v, d.value), nil
+}
+
+func (d *dummyHedgeCache) GetInterface(k string) (interface{}, bool) {
+	time.Sleep(d.sleep)
+	return d.value, d.value != ""
+}
+
+func (d *dummyHedgeCache) GetGobBytes(k string) ([]byte, bool) {
+	time.Sleep(d.sleep)
+	return []byte(d.value), d.value != ""
+}
+
+func (d *dummyHedgeCache) count() int32 {
+	return atomic.LoadInt32(&d.called)
+}
+
+func (d *dummyHedgeCache) cancelCount() int32 {
+	return atomic.LoadInt32(&d.cancelled)
+}
@@ -0,0 +1,271 @@
+package rediscache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/evalphobia/eurekache"
+	"github.com/garyburd/redigo/redis"
+)
+
+const (
+	busOpDelete        = "del"
+	busOpClear         = "clear"
+	busOpInvalidateTag = "tag"
+
+	defaultBusRetryInterval = time.Second
+)
+
+var errBusClosed = errors.New("InvalidationBus is closed")
+
+// busMessage is a message published on the channel
+type busMessage struct {
+	ID  string `json:"id"`
+	Op  string `json:"op"`
+	Key string `json:"key,omitempty"`
+}
+
+// InvalidationBus publishes changes of cached data on Redis channel,
+// and evicts the changed data from local cache sources when receiving the message from other instances.
+// Set the bus into Eurekache as Notifier.
+type InvalidationBus struct {
+	pool          *redis.Pool
+	channel       string
+	id            string
+	retryInterval time.Duration
+
+	cachesMu sync.RWMutex
+	caches   []eurekache.Cache
+
+	connMu sync.Mutex
+	conn   *redis.PubSubConn
+	closed bool
+}
+
+// NewInvalidationBus returns initialized InvalidationBus with given redis.Pool and channel name
+func NewInvalidationBus(pool *redis.Pool, channel string) *InvalidationBus {
+	return &InvalidationBus{
+		pool:          pool,
+		channel:       channel,
+		id:            newBusID(),
+		retryInterval: defaultBusRetryInterval,
+	}
+}
+
+// SetRetryInterval sets interval for re-subscribing after the connection is lost
+func (b *InvalidationBus) SetRetryInterval(d time.Duration) {
+	b.retryInterval = d
+}
+
+// AddCacheSource adds local cache source to evict data.
+func (b *InvalidationBus) AddCacheSource(cache eurekache.Cache) {
+	if cache == nil {
+		return
+	}
+
+	b.cachesMu.Lock()
+	defer b.cachesMu.Unlock()
+	b.caches = append(b.caches, cache)
+}
+
+// NotifyDelete publishes the key is changed
+func (b *InvalidationBus) NotifyDelete(key string) error {
+	return b.publish(busOpDelete, key)
+}
+
+// NotifyClear publishes all of data is deleted
+func (b *InvalidationBus) NotifyClear() error {
+	return b.publish(busOpClear, "")
+}
+
+// NotifyInvalidateTag publishes the tag is invalidated
+func (b *InvalidationBus) NotifyInvalidateTag(tag string) error {
+	return b.publish(busOpInvalidateTag, tag)
+}
+
+// publish publishes message on the channel
+func (b *InvalidationBus) publish(op, key string) error {
+	if b.pool == nil {
+		return errNilPool
+	}
+
+	msg, err := json.Marshal(busMessage{
+		ID:  b.id,
+		Op:  op,
+		Key: key,
+	})
+	if err != nil {
+		return err
+	}
+
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	_, err = conn.Do("PUBLISH", b.channel, msg)
+	return err
+}
+
+// Subscribe subscribes the channel and starts receiving messages on background.
+// When the connection is lost, local cache sources are cleared and the channel is subscribed again,
+// because messages may be missed during reconnection.
+func (b *InvalidationBus) Subscribe() error {
+	psc, err := b.subscribe()
+	if err != nil {
+		return err
+	}
+
+	go b.run(psc)
+	return nil
+}
+
+// Close stops receiving messages.
+// The connection is closed on background after the channel is unsubscribed.
+func (b *InvalidationBus) Close() error {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+
+	b.closed = true
+	if b.conn == nil {
+		return nil
+	}
+
+	err := b.conn.Unsubscribe()
+	b.conn = nil
+	return err
+}
+
+// subscribe subscribes the channel and waits for the confirmation
+func (b *InvalidationBus) subscribe() (*redis.PubSubConn, error) {
+	if b.pool == nil {
+		return nil, errNilPool
+	}
+
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+	if b.closed {
+		return nil, errBusClosed
+	}
+
+	psc := &redis.PubSubConn{Conn: b.pool.Get()}
+	err := psc.Subscribe(b.channel)
+	if err != nil {
+		psc.Close()
+		return nil, err
+	}
+
+	switch v := psc.Receive().(type) {
+	case error:
+		psc.Close()
+		return nil, v
+	}
+
+	b.conn = psc
+	return psc, nil
+}
+
+// run receives messages until the bus is closed
+func (b *InvalidationBus) run(psc *redis.PubSubConn) {
+	for {
+		b.receive(psc)
+		b.closeConn(psc)
+		if b.isClosed() {
+			return
+		}
+
+		for {
+			time.Sleep(b.retryInterval)
+			var err error
+			psc, err = b.subscribe()
+			if err == nil {
+				break
+			}
+			if b.isClosed() {
+				return
+			}
+		}
+		b.clearCaches()
+	}
+}
+
+// receive handles messages until error occurs or the channel is unsubscribed
+func (b *InvalidationBus) receive(psc *redis.PubSubConn) {
+	for {
+		switch v := psc.Receive().(type) {
+		case redis.Message:
+			b.handle(v.Data)
+		case redis.Subscription:
+			if v.Count == 0 {
+				return
+			}
+		case error:
+			return
+		}
+	}
+}
+
+// handle evicts data from local cache sources by the message
+func (b *InvalidationBus) handle(data []byte) {
+	var msg busMessage
+	err := json.Unmarshal(data, &msg)
+	switch {
+	case err != nil:
+		return
+	case msg.ID == b.id:
+		// published by itself
+		return
+	}
+
+	b.cachesMu.RLock()
+	defer b.cachesMu.RUnlock()
+	for _, c := range b.caches {
+		switch msg.Op {
+		case busOpDelete:
+			c.Set(msg.Key, nil)
+		case busOpClear:
+			c.Clear()
+		case busOpInvalidateTag:
+			if tc, ok := c.(eurekache.TagCache); ok {
+				tc.InvalidateTag(msg.Key)
+			}
+		}
+	}
+}
+
+// clearCaches deletes all of data from local cache sources
+func (b *InvalidationBus) clearCaches() {
+	b.cachesMu.RLock()
+	defer b.cachesMu.RUnlock()
+	for _, c := range b.caches {
+		c.Clear()
+	}
+}
+
+// closeConn closes the connection.
+// lock is needed because both of Close and Unsubscribe write to the connection.
+func (b *InvalidationBus) closeConn(psc *redis.PubSubConn) {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+
+	psc.Close()
+	if b.conn == psc {
+		b.conn = nil
+	}
+}
+
+// isClosed checks if the bus is closed or not
+func (b *InvalidationBus) isClosed() bool {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+	return b.closed
+}
+
+// newBusID returns random id to identify the instance
+func newBusID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,95 @@
+package rediscache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/evalphobia/eurekache"
+	"github.com/evalphobia/eurekache/memorycache"
+	"github.com/evalphobia/eurekache/test/helper"
+)
+
+var testBusChannel = "eurekache:bus"
+
+func TestNewInvalidationBus(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := helper.TestGetPool()
+	b := NewInvalidationBus(pool, testBusChannel)
+	assert.NotNil(b)
+	assert.Equal(pool, b.pool)
+	assert.Equal(testBusChannel, b.channel)
+	assert.Len(b.id, 32)
+	assert.Equal(defaultBusRetryInterval, b.retryInterval)
+
+	b2 := NewInvalidationBus(pool, testBusChannel)
+	assert.NotEqual(b.id, b2.id)
+}
+
+func TestInvalidationBus(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := helper.TestGetPool()
+	m1 := memorycache.NewCacheTTL(10)
+	b1 := NewInvalidationBus(pool, testBusChannel)
+	b1.AddCacheSource(m1)
+	defer b1.Close()
+
+	m2 := memorycache.NewCacheTTL(10)
+	b2 := NewInvalidationBus(pool, testBusChannel)
+	b2.AddCacheSource(m2)
+	defer b2.Close()
+
+	assert.NoError(b1.Subscribe())
+	assert.NoError(b2.Subscribe())
+
+	e1 := eurekache.New()
+	e1.SetCacheSources([]eurekache.Cache{m1})
+	e1.SetNotifier(b1)
+
+	e2 := eurekache.New()
+	e2.SetCacheSources([]eurekache.Cache{m2})
+	e2.SetNotifier(b2)
+
+	var result string
+
+	// delete
+	e2.Set("key", "value2")
+	time.Sleep(100 * time.Millisecond)
+	e1.Set("key", "value1")
+	time.Sleep(100 * time.Millisecond)
+	assert.True(m1.Get("key", &result))
+	assert.Equal("value1", result)
+	assert.False(m2.Get("key", &result))
+
+	// invalidate tag
+	m1.SetWithTags("tagged", "value1", 0, "tag")
+	m2.SetWithTags("tagged", "value2", 0, "tag")
+	assert.NoError(e2.InvalidateTag("tag"))
+	assert.False(m2.Get("tagged", &result))
+	time.Sleep(100 * time.Millisecond)
+	assert.False(m1.Get("tagged", &result))
+
+	// clear
+	m1.Set("key1", "value1")
+	m2.Set("key2", "value2")
+	assert.NoError(e2.ClearAll())
+	time.Sleep(100 * time.Millisecond)
+	assert.False(m1.Get("key1", &result))
+}
+
+func TestInvalidationBusClose(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewInvalidationBus(helper.TestGetPool(), testBusChannel)
+	assert.NoError(b.Subscribe())
+	assert.NoError(b.Close())
+	assert.True(b.isClosed())
+	assert.Equal(errBusClosed, b.Subscribe())
+
+	b = NewInvalidationBus(nil, testBusChannel)
+	assert.Equal(errNilPool, b.Subscribe())
+	assert.Equal(errNilPool, b.NotifyDelete("key"))
+}
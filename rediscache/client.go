@@ -0,0 +1,188 @@
+package rediscache
+
+import (
+	"context"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// Client is interface of redis client used by RedisCache
+type Client interface {
+	// Get returns the value of the key, and returns nil without error when the key does not exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set sets the value with TTL. The value never expires when ttl is zero or less.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Del deletes the keys.
+	Del(ctx context.Context, keys ...string) error
+	// Expire sets TTL of the key.
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	// MGet returns the values of the keys, and the value is nil when the key does not exist.
+	MGet(ctx context.Context, keys ...string) ([][]byte, error)
+	// Scan iterates the keys matching the pattern, and returns the keys and the next cursor.
+	Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error)
+	// Pipeline sends the commands queued by fn at once and returns the first error of the replies.
+	Pipeline(ctx context.Context, fn func(Pipeliner)) error
+	// Do sends the command and returns the reply.
+	Do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error)
+}
+
+// Pipeliner queues commands sent by Client.Pipeline
+type Pipeliner interface {
+	Set(key string, value []byte, ttl time.Duration)
+	Del(keys ...string)
+	Expire(key string, ttl time.Duration)
+}
+
+// NewRedigoClient returns Client using redis.Pool of redigo
+func NewRedigoClient(pool *redis.Pool) Client {
+	return redigoClient{
+		conn: func(ctx context.Context) (redis.Conn, error) {
+			if pool == nil {
+				return nil, errNilPool
+			}
+			conn, err := pool.GetContext(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return newContextConn(conn), nil
+		},
+	}
+}
+
+// redigoClient is Client sending commands through redis.Conn of redigo
+type redigoClient struct {
+	conn func(context.Context) (redis.Conn, error)
+}
+
+// Get sends GET
+func (c redigoClient) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := redis.Bytes(c.Do(ctx, "GET", key))
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	return b, err
+}
+
+// Set sends SET
+func (c redigoClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	cmd, args := setArgs(key, value, ttl)
+	_, err := c.Do(ctx, cmd, args...)
+	return err
+}
+
+// Del sends DEL
+func (c redigoClient) Del(ctx context.Context, keys ...string) error {
+	_, err := c.Do(ctx, "DEL", redis.Args{}.AddFlat(keys)...)
+	return err
+}
+
+// Expire sends PEXPIRE
+func (c redigoClient) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	_, err := c.Do(ctx, "PEXPIRE", key, ttl.Milliseconds())
+	return err
+}
+
+// MGet sends MGET
+func (c redigoClient) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	return redis.ByteSlices(c.Do(ctx, "MGET", redis.Args{}.AddFlat(keys)...))
+}
+
+// Scan sends SCAN
+func (c redigoClient) Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	values, err := redis.Values(c.Do(ctx, "SCAN", cursor, "MATCH", match, "COUNT", count))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var keys []string
+	_, err = redis.Scan(values, &cursor, &keys)
+	if err != nil {
+		return nil, 0, err
+	}
+	return keys, cursor, nil
+}
+
+// Pipeline sends the commands by Send, Flush and Receive
+func (c redigoClient) Pipeline(ctx context.Context, fn func(Pipeliner)) error {
+	conn, err := c.conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	p := &redigoPipeliner{conn: conn}
+	fn(p)
+	if p.err != nil {
+		return p.err
+	}
+
+	err = conn.Flush()
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for i := 0; i < p.count; i++ {
+		_, err = conn.Receive()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Do sends the command until the deadline of ctx
+func (c redigoClient) Do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	conn, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return doContext(ctx, conn, cmd, args...)
+}
+
+// redigoPipeliner queues commands by redis.Conn.Send
+type redigoPipeliner struct {
+	conn  redis.Conn
+	count int
+	err   error
+}
+
+// Set queues SET
+func (p *redigoPipeliner) Set(key string, value []byte, ttl time.Duration) {
+	cmd, args := setArgs(key, value, ttl)
+	p.send(cmd, args...)
+}
+
+// Del queues DEL
+func (p *redigoPipeliner) Del(keys ...string) {
+	p.send("DEL", redis.Args{}.AddFlat(keys)...)
+}
+
+// Expire queues PEXPIRE
+func (p *redigoPipeliner) Expire(key string, ttl time.Duration) {
+	p.send("PEXPIRE", key, ttl.Milliseconds())
+}
+
+// send queues the command and keeps the first error
+func (p *redigoPipeliner) send(cmd string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	p.err = p.conn.Send(cmd, args...)
+	p.count++
+}
+
+// setArgs returns SET command and arguments with TTL.
+// TTL in whole seconds is set by SETEX, otherwise by PX option.
+func setArgs(key string, value []byte, ttl time.Duration) (string, []interface{}) {
+	switch {
+	case ttl <= 0:
+		return "SET", []interface{}{key, value}
+	case ttl%time.Second == 0:
+		return "SETEX", []interface{}{key, int64(ttl / time.Second), value}
+	}
+	return "SET", []interface{}{key, value, "PX", ttl.Milliseconds()}
+}
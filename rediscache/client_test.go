@@ -0,0 +1,170 @@
+package rediscache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/evalphobia/eurekache/test/helper"
+)
+
+func TestRedigoClient(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	pool := helper.TestGetPool()
+	c := NewRedigoClient(pool)
+
+	key := testRedisPrefix + "keyTestRedigoClient"
+	assert.NoError(c.Set(ctx, key, []byte("value"), time.Minute))
+	b, err := c.Get(ctx, key)
+	assert.NoError(err)
+	assert.Equal([]byte("value"), b)
+
+	ttl, _ := redis.Int64(pool.Get().Do("PTTL", key))
+	assert.True(ttl > 59000)
+
+	assert.NoError(c.Set(ctx, key, []byte("value"), 1500*time.Millisecond))
+	ttl, _ = redis.Int64(pool.Get().Do("PTTL", key))
+	assert.True(ttl > 1000 && ttl <= 1500)
+
+	assert.NoError(c.Expire(ctx, key, 10*time.Second))
+	ttl, _ = redis.Int64(pool.Get().Do("PTTL", key))
+	assert.True(ttl > 1500 && ttl <= 10000)
+
+	values, err := c.MGet(ctx, key, testRedisPrefix+"nokey")
+	assert.NoError(err)
+	assert.Equal([][]byte{[]byte("value"), nil}, values)
+
+	keys, _, err := c.Scan(ctx, 0, testRedisPrefix+"keyTestRedigoClient*", 100)
+	assert.NoError(err)
+	assert.Contains(keys, key)
+
+	assert.NoError(c.Del(ctx, key))
+	b, err = c.Get(ctx, key)
+	assert.NoError(err)
+	assert.Nil(b)
+
+	// pipeline
+	assert.NoError(c.Pipeline(ctx, func(p Pipeliner) {
+		p.Set(key, []byte("value1"), 0)
+		p.Set(key+"2", []byte("value2"), 0)
+		p.Expire(key, time.Minute)
+		p.Del(key + "2")
+	}))
+	values, _ = c.MGet(ctx, key, key+"2")
+	assert.Equal([][]byte{[]byte("value1"), nil}, values)
+
+	// error reply
+	_, err = c.Do(ctx, "INCR", key)
+	assert.IsType(redis.Error(""), err)
+
+	// nil pool
+	_, err = NewRedigoClient(nil).Get(ctx, key)
+	assert.Equal(errNilPool, err)
+}
+
+func TestNewRedisCacheWithClient(t *testing.T) {
+	assert := assert.New(t)
+
+	client := newDummyClient()
+	c := NewRedisCacheWithClient(client)
+	c.SetPrefix(testRedisPrefix)
+	assert.Equal("redis", c.Name())
+	assert.NoError(c.Ping(context.Background()))
+
+	assert.NoError(c.Set("key", "value"))
+	assert.Contains(client.data, testRedisPrefix+"key")
+
+	var v string
+	assert.True(c.Get("key", &v))
+	assert.Equal("value", v)
+
+	// tags are sent by Do
+	assert.NoError(c.SetWithTags("key2", "value2", 1000, "tag"))
+	assert.Equal([]string{"PING", "TTL", "SADD", "EXPIRE"}, client.commands)
+
+	assert.NoError(c.SetExpire("key", nil, 0))
+	assert.False(c.Get("key", &v))
+}
+
+// dummyClient is in-memory Client without tag commands
+type dummyClient struct {
+	data     map[string][]byte
+	commands []string
+}
+
+func newDummyClient() *dummyClient {
+	return &dummyClient{
+		data: make(map[string][]byte),
+	}
+}
+
+func (c *dummyClient) Get(ctx context.Context, key string) ([]byte, error) {
+	return c.data[key], nil
+}
+
+func (c *dummyClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.data[key] = value
+	return nil
+}
+
+func (c *dummyClient) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(c.data, key)
+	}
+	return nil
+}
+
+func (c *dummyClient) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return nil
+}
+
+func (c *dummyClient) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = c.data[key]
+	}
+	return values, nil
+}
+
+func (c *dummyClient) Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	var keys []string
+	for key := range c.data {
+		keys = append(keys, key)
+	}
+	return keys, 0, nil
+}
+
+func (c *dummyClient) Pipeline(ctx context.Context, fn func(Pipeliner)) error {
+	fn(dummyPipeliner{c})
+	return nil
+}
+
+func (c *dummyClient) Do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	c.commands = append(c.commands, cmd)
+	switch cmd {
+	case "TTL":
+		return int64(-2), nil
+	case "SMEMBERS":
+		return []interface{}{}, nil
+	}
+	return "OK", nil
+}
+
+type dummyPipeliner struct {
+	c *dummyClient
+}
+
+func (p dummyPipeliner) Set(key string, value []byte, ttl time.Duration) {
+	p.c.Set(context.Background(), key, value, ttl)
+}
+
+func (p dummyPipeliner) Del(keys ...string) {
+	p.c.Del(context.Background(), keys...)
+}
+
+func (p dummyPipeliner) Expire(key string, ttl time.Duration) {}
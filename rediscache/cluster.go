@@ -0,0 +1,710 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evalphobia/eurekache"
+	"github.com/garyburd/redigo/redis"
+)
+
+const (
+	clusterSlots        = 16384
+	maxClusterRedirects = 5
+
+	defaultClusterMaxIdle     = 3
+	defaultClusterIdleTimeout = 240 * time.Second
+	defaultClusterDialTimeout = time.Second
+)
+
+var errNoClusterNode = errors.New("no node is available in redis cluster")
+
+// ClusterCache is a cache source for Redis Cluster.
+// Keys are routed to the node by hash slot, and MOVED and ASK redirections are followed.
+// When the key contains hash tag (e.g. "{user:1}:profile"), only the tag is used for the slot,
+// so the keys having the same tag are stored in the same node.
+type ClusterCache struct {
+	newPool func(addr string) *redis.Pool
+	seeds   []string
+
+	mu     sync.RWMutex
+	pools  map[string]*redis.Pool
+	slots  []string
+	loaded bool
+
+	prefix      string
+	defaultTTL  int64
+	jitter      eurekache.Jitter
+	name        string
+	retryPolicy RetryPolicy
+}
+
+// NewClusterCache returns initialized ClusterCache with the addresses of cluster nodes.
+// newPool creates redis.Pool for the node address, and the pool dialing the address by TCP is used when it's nil.
+// The default pool keeps idle connections for 240 seconds, and connecting and writing are timed out after a second.
+// SELECT is never sent because Redis Cluster supports only db 0.
+func NewClusterCache(addrs []string, newPool func(addr string) *redis.Pool) *ClusterCache {
+	if newPool == nil {
+		newPool = newClusterPool
+	}
+
+	return &ClusterCache{
+		newPool: newPool,
+		seeds:   addrs,
+		pools:   make(map[string]*redis.Pool),
+		name:    "redis-cluster",
+	}
+}
+
+// SetName sets the name of the cache source
+func (c *ClusterCache) SetName(name string) {
+	c.name = name
+}
+
+// Name returns the name of the cache source
+func (c *ClusterCache) Name() string {
+	return c.name
+}
+
+// SetTTL sets default TTL (milliseconds)
+func (c *ClusterCache) SetTTL(ttl int64) {
+	c.defaultTTL = ttl
+}
+
+// SetJitter sets jitter to randomize TTL
+func (c *ClusterCache) SetJitter(j eurekache.Jitter) {
+	c.jitter = j
+}
+
+// SetPrefix sets prefix for all of keys. the slot is calculated with the prefix.
+func (c *ClusterCache) SetPrefix(prefix string) {
+	c.prefix = prefix
+}
+
+// SetRetryPolicy sets policy for retrying GET, SET, DEL and batch operations
+func (c *ClusterCache) SetRetryPolicy(p RetryPolicy) {
+	c.retryPolicy = p
+}
+
+// Ping checks the connections to all of master nodes until the deadline of ctx
+func (c *ClusterCache) Ping(ctx context.Context) error {
+	err := c.loadSlots(ctx)
+	if err != nil {
+		return wrapConnError(err)
+	}
+
+	for _, addr := range c.masters() {
+		_, err = c.doNode(ctx, addr, false, "PING")
+		if err != nil {
+			return wrapConnError(err)
+		}
+	}
+	return nil
+}
+
+// Close closes all of pools for the nodes
+func (c *ClusterCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var firstErr error
+	for addr, pool := range c.pools {
+		err := pool.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(c.pools, addr)
+	}
+	return firstErr
+}
+
+// Get searches cache by given key from redis cluster and returns flag of cache is existed or not.
+// when cache hit, data is assigned.
+func (c *ClusterCache) Get(key string, data interface{}) bool {
+	ok, _ := c.Lookup(key, data)
+	return ok
+}
+
+// Lookup searches cache by given key from redis cluster and returns flag of cache is existed or not, and error.
+func (c *ClusterCache) Lookup(key string, data interface{}) (bool, error) {
+	_, ok, err := c.LookupItem(context.Background(), key, data)
+	return ok, err
+}
+
+// LookupContext searches cache like Lookup, and redis command is timed out at the deadline of ctx.
+func (c *ClusterCache) LookupContext(ctx context.Context, key string, data interface{}) (bool, error) {
+	_, ok, err := c.LookupItem(ctx, key, data)
+	return ok, err
+}
+
+// LookupItem searches cache by given key from redis cluster and returns Item and error.
+func (c *ClusterCache) LookupItem(ctx context.Context, key string, data interface{}) (*eurekache.Item, bool, error) {
+	item, ok, err := c.getItem(ctx, key)
+	switch {
+	case !ok:
+		return nil, false, err
+	case item.Value == nil:
+		return nil, false, nil
+	}
+
+	err = item.Assign(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return item, true, nil
+}
+
+// GetInterface searches cache by given key from redis cluster and returns interface value.
+func (c *ClusterCache) GetInterface(key string) (interface{}, bool) {
+	item, ok, _ := c.getItem(context.Background(), key)
+	if !ok {
+		return nil, false
+	}
+	return item.Value, true
+}
+
+// GetGobBytes searches cache by given key from redis cluster and returns gob-encoded value.
+func (c *ClusterCache) GetGobBytes(key string) ([]byte, bool) {
+	item, ok, _ := c.getItem(context.Background(), key)
+	if !ok {
+		return nil, false
+	}
+	return item.GobBytes()
+}
+
+// GetMulti searches multiple cache by given keys from redis cluster, and returns values of the keys cache hit.
+// MGET is refused across hash slots, so the keys are grouped by slot,
+// and MGET for each slot is sent by pipeline for each node in parallel.
+func (c *ClusterCache) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	slots := make(map[int][]string)
+	var order []int
+	for _, key := range keys {
+		slot := HashSlot(c.prefix + key)
+		if _, ok := slots[slot]; !ok {
+			order = append(order, slot)
+		}
+		slots[slot] = append(slots[slot], key)
+	}
+
+	cmds := make([]clusterCommand, len(order))
+	for i, slot := range order {
+		args := make([]interface{}, len(slots[slot]))
+		for j, key := range slots[slot] {
+			args[j] = c.prefix + key
+		}
+		cmds[i] = clusterCommand{
+			key:  c.prefix + slots[slot][0],
+			name: "MGET",
+			args: args,
+		}
+	}
+
+	var replies []interface{}
+	err := c.retryPolicy.run(ctx, func(ctx context.Context) error {
+		var err error
+		replies, err = c.pipeline(ctx, cmds)
+		return err
+	})
+	if err != nil {
+		return nil, wrapConnError(err)
+	}
+
+	result := make(map[string]interface{}, len(keys))
+	for i, slot := range order {
+		values, err := redis.ByteSlices(replies[i], nil)
+		if err != nil {
+			return nil, err
+		}
+		for j, b := range values {
+			if b == nil || j >= len(slots[slot]) {
+				continue
+			}
+			item, err := eurekache.DecodeItem(b)
+			if err != nil || item.Value == nil {
+				continue
+			}
+			result[slots[slot][j]] = item.Value
+		}
+	}
+	return result, nil
+}
+
+// getItem sends GET to the node of the key and returns Item data and error
+func (c *ClusterCache) getItem(ctx context.Context, key string) (*eurekache.Item, bool, error) {
+	var item *eurekache.Item
+	err := c.retryPolicy.run(ctx, func(ctx context.Context) error {
+		b, err := redis.Bytes(c.do(ctx, c.prefix+key, "GET", c.prefix+key))
+		switch {
+		case err == redis.ErrNil:
+			return nil
+		case err != nil:
+			return wrapConnError(err)
+		}
+
+		item, err = eurekache.DecodeItem(b)
+		return err
+	})
+	if err != nil || item == nil {
+		return nil, false, err
+	}
+	return item, true, nil
+}
+
+// Set sets data into redis cluster. data is wrapped by gob-encoded Item
+func (c *ClusterCache) Set(key string, data interface{}) error {
+	return c.SetExpire(key, data, c.defaultTTL)
+}
+
+// SetExpire sets data into redis cluster with TTL.
+func (c *ClusterCache) SetExpire(key string, data interface{}, ttl int64) error {
+	return c.SetWithTags(key, data, ttl)
+}
+
+// SetWithTags sets data into redis cluster with TTL and tags.
+// The set of tag is stored in the node of the tag, and it's colocated with the keys when they have the same hash tag.
+func (c *ClusterCache) SetWithTags(key string, data interface{}, ttl int64, tags ...string) error {
+	return c.retryPolicy.run(context.Background(), func(ctx context.Context) error {
+		ttl := c.jitter.Apply(ttl)
+		cmd, args, err := clusterSetArgs(c.prefix+key, data, ttl)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.do(ctx, c.prefix+key, cmd, args...)
+		if err != nil || data == nil {
+			return err
+		}
+
+		for _, tag := range tags {
+			tagKey := c.prefix + tagKeyPrefix + tag
+			do := func(cmd string, args ...interface{}) (interface{}, error) {
+				return c.do(ctx, tagKey, cmd, args...)
+			}
+			err = addTag(do, tagKey, key, ceilSeconds(ttl))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetMulti sets multiple data into redis cluster.
+// Commands are grouped by node and sent by pipeline in parallel.
+// Data with tags is set by SetWithTags after the pipelines.
+func (c *ClusterCache) SetMulti(entries []eurekache.WriteEntry) error {
+	var tagged []eurekache.WriteEntry
+	var cmds []clusterCommand
+	for _, e := range entries {
+		if len(e.Tags) != 0 {
+			tagged = append(tagged, e)
+			continue
+		}
+
+		ttl := e.TTL
+		if e.UseDefaultTTL {
+			ttl = c.defaultTTL
+		}
+		cmd, args, err := clusterSetArgs(c.prefix+e.Key, e.Value, c.jitter.Apply(ttl))
+		if err != nil {
+			return err
+		}
+		cmds = append(cmds, clusterCommand{
+			key:  c.prefix + e.Key,
+			name: cmd,
+			args: args,
+		})
+	}
+
+	err := c.retryPolicy.run(context.Background(), func(ctx context.Context) error {
+		_, err := c.pipeline(ctx, cmds)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range tagged {
+		err := c.SetWithTags(e.Key, e.Value, e.TTL, e.Tags...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InvalidateTag deletes all of cached data related to the tag from redis cluster.
+// Keys are deleted by pipeline for each node.
+func (c *ClusterCache) InvalidateTag(tag string) error {
+	return c.retryPolicy.run(context.Background(), func(ctx context.Context) error {
+		tagKey := c.prefix + tagKeyPrefix + tag
+		keys, err := redis.Strings(c.do(ctx, tagKey, "SMEMBERS", tagKey))
+		if err != nil {
+			return err
+		}
+
+		cmds := make([]clusterCommand, 0, len(keys)+1)
+		for _, key := range keys {
+			cmds = append(cmds, clusterCommand{
+				key:  c.prefix + key,
+				name: "DEL",
+				args: []interface{}{c.prefix + key},
+			})
+		}
+		_, err = c.pipeline(ctx, cmds)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.do(ctx, tagKey, "DEL", tagKey)
+		return err
+	})
+}
+
+// Clear does nothing on ClusterCache.
+func (c *ClusterCache) Clear() error {
+	return nil
+}
+
+// clusterSetArgs returns SET or DEL command and arguments to set data wrapped by gob-encoded Item.
+// TTL (milliseconds) is kept in sub-second precision by setArgs.
+func clusterSetArgs(key string, data interface{}, ttl int64) (string, []interface{}, error) {
+	if data == nil {
+		return "DEL", []interface{}{key}, nil
+	}
+
+	b, err := eurekache.EncodeItem(data, ttl)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cmd, args := setArgs(key, b, msDuration(ttl))
+	return cmd, args, nil
+}
+
+// clusterCommand is a single-key command sent to redis cluster
+type clusterCommand struct {
+	key  string
+	name string
+	args []interface{}
+}
+
+// pipeline groups the commands by node and sends them by pipeline for each node in parallel,
+// and returns the replies in the order of the commands.
+// redirected commands are sent again one by one.
+func (c *ClusterCache) pipeline(ctx context.Context, cmds []clusterCommand) ([]interface{}, error) {
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	groups := make(map[string][]int)
+	for i, cmd := range cmds {
+		addr, err := c.nodeAddr(ctx, cmd.key)
+		if err != nil {
+			return nil, err
+		}
+		groups[addr] = append(groups[addr], i)
+	}
+
+	// each goroutine writes the replies of its own commands
+	replies := make([]interface{}, len(cmds))
+	var mu sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+	for addr, indexes := range groups {
+		wg.Add(1)
+		go func(addr string, indexes []int) {
+			defer wg.Done()
+			err := c.pipelineNode(ctx, addr, cmds, indexes, replies)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(addr, indexes)
+	}
+	wg.Wait()
+	return replies, firstErr
+}
+
+// pipelineNode sends the commands of the indexes to the node by pipeline
+func (c *ClusterCache) pipelineNode(ctx context.Context, addr string, cmds []clusterCommand, indexes []int, replies []interface{}) error {
+	conn, err := c.pool(addr).GetContext(ctx)
+	if err != nil {
+		c.invalidateSlots()
+		return err
+	}
+	defer conn.Close()
+
+	for _, i := range indexes {
+		err = conn.Send(cmds[i].name, cmds[i].args...)
+		if err != nil {
+			return err
+		}
+	}
+	err = conn.Flush()
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, i := range indexes {
+		reply, err := conn.Receive()
+		if _, _, ok := parseRedirect(err); ok {
+			// moved by resharding
+			reply, err = c.do(ctx, cmds[i].key, cmds[i].name, cmds[i].args...)
+		}
+		replies[i] = reply
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// do sends the command to the node of the key, and follows MOVED and ASK redirections
+func (c *ClusterCache) do(ctx context.Context, key string, cmd string, args ...interface{}) (interface{}, error) {
+	addr, err := c.nodeAddr(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	asking := false
+	for i := 0; ; i++ {
+		reply, err := c.doNode(ctx, addr, asking, cmd, args...)
+		kind, target, ok := parseRedirect(err)
+		if !ok || i >= maxClusterRedirects {
+			return reply, err
+		}
+
+		switch kind {
+		case "MOVED":
+			// the slot map is reloaded on the next command
+			c.invalidateSlots()
+			asking = false
+		case "ASK":
+			asking = true
+		}
+		addr = target
+	}
+}
+
+// doNode sends the command to the node
+func (c *ClusterCache) doNode(ctx context.Context, addr string, asking bool, cmd string, args ...interface{}) (interface{}, error) {
+	conn, err := c.pool(addr).GetContext(ctx)
+	if err != nil {
+		c.invalidateSlots()
+		return nil, err
+	}
+	conn = newContextConn(conn)
+	defer conn.Close()
+
+	if asking {
+		_, err = doContext(ctx, conn, "ASKING")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := doContext(ctx, conn, cmd, args...)
+	if err != nil {
+		if _, ok := err.(redis.Error); !ok {
+			c.invalidateSlots()
+		}
+	}
+	return reply, err
+}
+
+// nodeAddr returns the address of the master node for the key
+func (c *ClusterCache) nodeAddr(ctx context.Context, key string) (string, error) {
+	c.mu.RLock()
+	loaded := c.loaded
+	c.mu.RUnlock()
+
+	if !loaded {
+		err := c.loadSlots(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	addr := c.slots[HashSlot(key)]
+	if addr == "" {
+		return "", errNoClusterNode
+	}
+	return addr, nil
+}
+
+// loadSlots loads the slot map by CLUSTER SLOTS from one of known nodes
+func (c *ClusterCache) loadSlots(ctx context.Context) error {
+	lastErr := errNoClusterNode
+	for _, addr := range c.knownAddrs() {
+		slots, err := c.fetchSlots(ctx, addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		c.mu.Lock()
+		c.slots = slots
+		c.loaded = true
+		c.mu.Unlock()
+		return nil
+	}
+	return lastErr
+}
+
+// fetchSlots sends CLUSTER SLOTS to the node and returns the slot map
+func (c *ClusterCache) fetchSlots(ctx context.Context, addr string) ([]string, error) {
+	conn, err := c.pool(addr).GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conn = newContextConn(conn)
+	defer conn.Close()
+
+	ranges, err := redis.Values(doContext(ctx, conn, "CLUSTER", "SLOTS"))
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make([]string, clusterSlots)
+	for _, r := range ranges {
+		// [start, end, [host, port, id], replicas...]
+		values, err := redis.Values(r, nil)
+		if err != nil || len(values) < 3 {
+			return nil, errors.New("invalid reply of CLUSTER SLOTS")
+		}
+		start, _ := redis.Int(values[0], nil)
+		end, _ := redis.Int(values[1], nil)
+		master, err := redis.Values(values[2], nil)
+		if err != nil || len(master) < 2 {
+			return nil, errors.New("invalid reply of CLUSTER SLOTS")
+		}
+		host, _ := redis.String(master[0], nil)
+		port, _ := redis.Int(master[1], nil)
+		if host == "" {
+			// empty host means the same host as the queried node
+			host, _, _ = net.SplitHostPort(addr)
+		}
+
+		nodeAddr := net.JoinHostPort(host, strconv.Itoa(port))
+		for slot := start; slot <= end && slot < clusterSlots; slot++ {
+			slots[slot] = nodeAddr
+		}
+	}
+	return slots, nil
+}
+
+// invalidateSlots marks the slot map to be reloaded
+func (c *ClusterCache) invalidateSlots() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loaded = false
+}
+
+// knownAddrs returns the seed addresses and the addresses in the slot map
+func (c *ClusterCache) knownAddrs() []string {
+	addrs := append([]string{}, c.seeds...)
+	return append(addrs, c.masters()...)
+}
+
+// masters returns the addresses of master nodes in the slot map
+func (c *ClusterCache) masters() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var addrs []string
+	seen := make(map[string]bool)
+	for _, addr := range c.slots {
+		if addr != "" && !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// newClusterPool creates the default redis.Pool for the node address
+func newClusterPool(addr string) *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:     defaultClusterMaxIdle,
+		IdleTimeout: defaultClusterIdleTimeout,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", addr,
+				redis.DialConnectTimeout(defaultClusterDialTimeout),
+				redis.DialWriteTimeout(defaultClusterDialTimeout),
+			)
+		},
+	}
+}
+
+// pool returns redis.Pool for the node address
+func (c *ClusterCache) pool(addr string) *redis.Pool {
+	c.mu.RLock()
+	p, ok := c.pools[addr]
+	c.mu.RUnlock()
+	if ok {
+		return p
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.pools[addr]; ok {
+		return p
+	}
+	p = c.newPool(addr)
+	c.pools[addr] = p
+	return p
+}
+
+// parseRedirect parses MOVED or ASK error, and returns the kind and the target address
+func parseRedirect(err error) (string, string, bool) {
+	redisErr, ok := err.(redis.Error)
+	if !ok {
+		return "", "", false
+	}
+
+	// e.g.) MOVED 3999 127.0.0.1:6381
+	fields := strings.Fields(string(redisErr))
+	if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+		return "", "", false
+	}
+	return fields[0], fields[2], true
+}
+
+// HashSlot returns the hash slot of the key in Redis Cluster.
+// When the key contains hash tag (non-empty string between the first "{" and the following "}"), only the tag is hashed.
+func HashSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16([]byte(key)) % clusterSlots)
+}
+
+// crc16 returns CRC16-CCITT (XModem) checksum used by Redis Cluster
+func crc16(b []byte) uint16 {
+	var crc uint16
+	for _, v := range b {
+		crc ^= uint16(v) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
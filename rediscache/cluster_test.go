@@ -0,0 +1,466 @@
+package rediscache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/evalphobia/eurekache"
+)
+
+func TestHashSlot(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(12182, HashSlot("foo"))
+	assert.Equal(0x31C3, HashSlot("123456789"))
+
+	// hash tag
+	assert.Equal(HashSlot("user1000"), HashSlot("{user1000}.following"))
+	assert.Equal(HashSlot("{user1000}.following"), HashSlot("{user1000}.followers"))
+	assert.Equal(HashSlot("bar"), HashSlot("foo{bar}{zap}"))
+	assert.Equal(HashSlot("{bar"), HashSlot("foo{{bar}}zap"))
+
+	// empty hash tag uses whole key
+	assert.Equal(int(crc16([]byte("foo{}{bar}"))%clusterSlots), HashSlot("foo{}{bar}"))
+}
+
+func TestClusterCache(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewClusterCache([]string{"127.0.0.1:6379"}, nil)
+	defer c.Close()
+	if _, err := c.fetchSlots(context.Background(), "127.0.0.1:6379"); err != nil {
+		t.Skipf("redis cluster is not available: %s", err)
+	}
+
+	c.SetPrefix(testRedisPrefix)
+	c.SetTTL(10000)
+	assert.Equal("redis-cluster", c.Name())
+	assert.NoError(c.Ping(context.Background()))
+
+	key := "keyTestClusterCache"
+	assert.NoError(c.Set(key, "value"))
+
+	var v string
+	ok, err := c.Lookup(key, &v)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("value", v)
+
+	iv, ok := c.GetInterface(key)
+	assert.True(ok)
+	assert.Equal("value", iv)
+
+	_, ok = c.GetGobBytes(key)
+	assert.True(ok)
+
+	// tags
+	assert.NoError(c.SetWithTags("{tag}:key1", "value1", 10000, "{tag}"))
+	assert.NoError(c.SetWithTags("{tag}:key2", "value2", 10000, "{tag}"))
+	assert.NoError(c.InvalidateTag("{tag}"))
+	assert.False(c.Get("{tag}:key1", &v))
+	assert.False(c.Get("{tag}:key2", &v))
+
+	// batch
+	assert.NoError(c.SetMulti([]eurekache.WriteEntry{
+		{Key: key, Value: nil},
+		{Key: "keyTestClusterCache2", Value: "value2", UseDefaultTTL: true},
+	}))
+	assert.False(c.Get(key, &v))
+	assert.True(c.Get("keyTestClusterCache2", &v))
+	assert.Equal("value2", v)
+}
+
+func TestClusterDefaultPool(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewClusterCache([]string{"127.0.0.1:6379"}, nil)
+	defer c.Close()
+
+	pool := c.pool("127.0.0.1:6379")
+	assert.Equal(defaultClusterMaxIdle, pool.MaxIdle)
+	assert.Equal(defaultClusterIdleTimeout, pool.IdleTimeout)
+
+	conn := pool.Get()
+	if _, err := conn.Do("PING"); err != nil {
+		t.Skipf("redis is not available: %s", err)
+	}
+	conn.Close()
+
+	// the connection is kept for reuse
+	assert.Equal(1, pool.IdleCount())
+}
+
+func TestClusterCacheRedirect(t *testing.T) {
+	assert := assert.New(t)
+
+	cluster := newFakeCluster("node1:7000", "node2:7001")
+	c := NewClusterCache([]string{"node1:7000"}, cluster.newPool)
+
+	// all slots are on node1 at first
+	cluster.assign("node1:7000", 0, clusterSlots-1)
+	assert.NoError(c.Set("key", "value"))
+	assert.Equal(1, cluster.node("node1:7000").len())
+
+	// MOVED: the slot is migrated into node2
+	slot := HashSlot("key")
+	cluster.node("node2:7001").copyFrom(cluster.node("node1:7000"))
+	cluster.node("node1:7000").redirect(slot, "MOVED", "node2:7001")
+	cluster.assign("node2:7001", slot, slot)
+
+	var v string
+	ok, err := c.Lookup("key", &v)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("value", v)
+
+	// slot map is reloaded and the command is sent to node2 directly
+	node1Count := cluster.node("node1:7000").count()
+	assert.True(c.Get("key", &v))
+	assert.Equal(node1Count, cluster.node("node1:7000").count())
+
+	// ASK: the slot is being migrated from node2 into node1
+	cluster.node("node2:7001").redirect(slot, "ASK", "node1:7000")
+	cluster.node("node1:7000").redirect(slot, "", "")
+	cluster.node("node1:7000").requireAsking(slot)
+	assert.NoError(c.Set("key", "value2"))
+	assert.True(c.Get("key", &v))
+	assert.Equal("value2", v)
+}
+
+func TestClusterCachePipeline(t *testing.T) {
+	assert := assert.New(t)
+
+	cluster := newFakeCluster("node1:7000", "node2:7001")
+	cluster.assign("node1:7000", 0, 8191)
+	cluster.assign("node2:7001", 8192, clusterSlots-1)
+	c := NewClusterCache([]string{"node1:7000"}, cluster.newPool)
+
+	var entries []eurekache.WriteEntry
+	for i := 0; i < 20; i++ {
+		entries = append(entries, eurekache.WriteEntry{
+			Key:   "key" + strconv.Itoa(i),
+			Value: i,
+		})
+	}
+	assert.NoError(c.SetMulti(entries))
+
+	// each node receives its keys
+	n1 := cluster.node("node1:7000")
+	n2 := cluster.node("node2:7001")
+	assert.Equal(20, n1.len()+n2.len())
+	assert.True(n1.len() > 0)
+	assert.True(n2.len() > 0)
+	for _, e := range entries {
+		var v int
+		assert.True(c.Get(e.Key, &v))
+		assert.Equal(e.Value, v)
+	}
+
+	// redirected command in the pipeline is sent again
+	slot := HashSlot("key0")
+	owner, other := n1, n2
+	otherAddr := "node2:7001"
+	if slot >= 8192 {
+		owner, other = n2, n1
+		otherAddr = "node1:7000"
+	}
+	owner.redirect(slot, "MOVED", otherAddr)
+	cluster.assign(otherAddr, slot, slot)
+	assert.NoError(c.SetMulti([]eurekache.WriteEntry{{Key: "key0", Value: 100}}))
+	assert.Equal(1, other.sets("key0"))
+}
+
+func TestClusterSetArgs(t *testing.T) {
+	assert := assert.New(t)
+
+	// sub-second TTL is kept by PX
+	cmd, args, err := clusterSetArgs("key", "value", 1500)
+	assert.NoError(err)
+	assert.Equal("SET", cmd)
+	assert.Equal([]interface{}{"PX", int64(1500)}, args[2:])
+
+	cmd, args, err = clusterSetArgs("key", "value", 2000)
+	assert.NoError(err)
+	assert.Equal("SETEX", cmd)
+	assert.Equal(int64(2), args[1])
+
+	cmd, args, err = clusterSetArgs("key", nil, 2000)
+	assert.NoError(err)
+	assert.Equal("DEL", cmd)
+	assert.Equal([]interface{}{"key"}, args)
+}
+
+func TestClusterCacheGetMulti(t *testing.T) {
+	assert := assert.New(t)
+
+	cluster := newFakeCluster("node1:7000", "node2:7001")
+	cluster.assign("node1:7000", 0, 8191)
+	cluster.assign("node2:7001", 8192, clusterSlots-1)
+	c := NewClusterCache([]string{"node1:7000"}, cluster.newPool)
+
+	keys := []string{"{tag}:key1", "{tag}:key2", "miss"}
+	var entries []eurekache.WriteEntry
+	for i := 0; i < 20; i++ {
+		key := "key" + strconv.Itoa(i)
+		keys = append(keys, key)
+		entries = append(entries, eurekache.WriteEntry{Key: key, Value: i})
+	}
+	entries = append(entries,
+		eurekache.WriteEntry{Key: "{tag}:key1", Value: 100},
+		eurekache.WriteEntry{Key: "{tag}:key2", Value: 200},
+	)
+	assert.NoError(c.SetMulti(entries))
+
+	// keys of different slots and nodes are fetched
+	result, err := c.GetMulti(context.Background(), keys)
+	assert.NoError(err)
+	assert.Len(result, 22)
+	assert.Equal(100, result["{tag}:key1"])
+	assert.Equal(200, result["{tag}:key2"])
+	assert.Equal(3, result["key3"])
+	assert.NotContains(result, "miss")
+
+	// redirected MGET is sent again
+	slot := HashSlot("key0")
+	owner, other := cluster.node("node1:7000"), cluster.node("node2:7001")
+	otherAddr := "node2:7001"
+	if slot >= 8192 {
+		owner, other = other, owner
+		otherAddr = "node1:7000"
+	}
+	other.copyFrom(owner)
+	owner.redirect(slot, "MOVED", otherAddr)
+	cluster.assign(otherAddr, slot, slot)
+	result, err = c.GetMulti(context.Background(), []string{"key0"})
+	assert.NoError(err)
+	assert.Equal(0, result["key0"])
+}
+
+// fakeCluster is in-memory redis cluster for testing redirections
+type fakeCluster struct {
+	mu    sync.Mutex
+	nodes map[string]*fakeNode
+	slots []string
+}
+
+func newFakeCluster(addrs ...string) *fakeCluster {
+	c := &fakeCluster{
+		nodes: make(map[string]*fakeNode),
+		slots: make([]string, clusterSlots),
+	}
+	for _, addr := range addrs {
+		c.nodes[addr] = &fakeNode{
+			cluster:  c,
+			data:     make(map[string][]byte),
+			setCount: make(map[string]int),
+			moved:    make(map[int][2]string),
+			asking:   make(map[int]bool),
+		}
+	}
+	return c
+}
+
+func (c *fakeCluster) newPool(addr string) *redis.Pool {
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			node, ok := c.nodes[addr]
+			if !ok {
+				return nil, fmt.Errorf("unknown node: %s", addr)
+			}
+			return &fakeConn{node: node}, nil
+		},
+	}
+}
+
+func (c *fakeCluster) node(addr string) *fakeNode {
+	return c.nodes[addr]
+}
+
+func (c *fakeCluster) assign(addr string, start, end int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := start; i <= end; i++ {
+		c.slots[i] = addr
+	}
+}
+
+func (c *fakeCluster) slotsReply() []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var reply []interface{}
+	for start := 0; start < clusterSlots; {
+		end := start
+		for end+1 < clusterSlots && c.slots[end+1] == c.slots[start] {
+			end++
+		}
+		if addr := c.slots[start]; addr != "" {
+			host, port, _ := strings.Cut(addr, ":")
+			p, _ := strconv.Atoi(port)
+			reply = append(reply, []interface{}{
+				int64(start), int64(end),
+				[]interface{}{[]byte(host), int64(p), []byte("id")},
+			})
+		}
+		start = end + 1
+	}
+	return reply
+}
+
+type fakeNode struct {
+	cluster *fakeCluster
+
+	mu       sync.Mutex
+	data     map[string][]byte
+	setCount map[string]int
+	moved    map[int][2]string
+	asking   map[int]bool
+	called   int
+}
+
+func (n *fakeNode) redirect(slot int, kind, addr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if kind == "" {
+		delete(n.moved, slot)
+		return
+	}
+	n.moved[slot] = [2]string{kind, addr}
+}
+
+func (n *fakeNode) requireAsking(slot int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.asking[slot] = true
+}
+
+func (n *fakeNode) copyFrom(src *fakeNode) {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for k, v := range src.data {
+		n.data[k] = v
+	}
+}
+
+func (n *fakeNode) len() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.data)
+}
+
+func (n *fakeNode) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.called
+}
+
+func (n *fakeNode) sets(key string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.setCount[key]
+}
+
+func (n *fakeNode) handle(conn *fakeConn, cmd string, args []interface{}) (interface{}, error) {
+	cmd = strings.ToUpper(cmd)
+	switch cmd {
+	case "":
+		return nil, nil
+	case "CLUSTER":
+		return n.cluster.slotsReply(), nil
+	case "PING":
+		return "PONG", nil
+	case "SELECT":
+		return "OK", nil
+	case "ASKING":
+		conn.asking = true
+		return "OK", nil
+	}
+
+	asking := conn.asking
+	conn.asking = false
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.called++
+
+	key := fmt.Sprint(args[0])
+	slot := HashSlot(key)
+	if r, ok := n.moved[slot]; ok {
+		return nil, redis.Error(fmt.Sprintf("%s %d %s", r[0], slot, r[1]))
+	}
+	if n.asking[slot] && !asking {
+		return nil, redis.Error(fmt.Sprintf("MOVED %d elsewhere:0", slot))
+	}
+
+	switch cmd {
+	case "MGET":
+		values := make([]interface{}, len(args))
+		for i, arg := range args {
+			if HashSlot(fmt.Sprint(arg)) != slot {
+				return nil, redis.Error("CROSSSLOT Keys in request don't hash to the same slot")
+			}
+			if v, ok := n.data[fmt.Sprint(arg)]; ok {
+				values[i] = v
+			}
+		}
+		return values, nil
+	case "GET":
+		v, ok := n.data[key]
+		if !ok {
+			return nil, nil
+		}
+		return v, nil
+	case "SET":
+		n.data[key] = args[1].([]byte)
+		n.setCount[key]++
+		return "OK", nil
+	case "SETEX":
+		n.data[key] = args[2].([]byte)
+		n.setCount[key]++
+		return "OK", nil
+	case "DEL":
+		delete(n.data, key)
+		return int64(1), nil
+	}
+	return nil, redis.Error("ERR unknown command " + cmd)
+}
+
+type fakeConn struct {
+	node    *fakeNode
+	asking  bool
+	pending []fakeReply
+}
+
+type fakeReply struct {
+	reply interface{}
+	err   error
+}
+
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Err() error   { return nil }
+func (c *fakeConn) Flush() error { return nil }
+
+func (c *fakeConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return c.node.handle(c, cmd, args)
+}
+
+func (c *fakeConn) Send(cmd string, args ...interface{}) error {
+	reply, err := c.node.handle(c, cmd, args)
+	c.pending = append(c.pending, fakeReply{reply, err})
+	return nil
+}
+
+func (c *fakeConn) Receive() (interface{}, error) {
+	r := c.pending[0]
+	c.pending = c.pending[1:]
+	return r.reply, r.err
+}
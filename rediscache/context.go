@@ -0,0 +1,141 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+var errAbandonedConn = errors.New("redis.Conn is waiting the reply of the cancelled command")
+
+// newContextConn wraps redis.Conn to stop waiting the reply by doContext when the context is done.
+func newContextConn(conn redis.Conn) redis.Conn {
+	if _, ok := conn.(*contextConn); ok {
+		return conn
+	}
+	return &contextConn{Conn: conn}
+}
+
+// contextConn is redis.Conn stopping to wait the reply when the context is done.
+// The cancelled command keeps the connection until its reply is received,
+// and then the connection is closed (returned to the pool).
+type contextConn struct {
+	redis.Conn
+
+	mu        sync.Mutex
+	abandoned chan struct{} // closed when the cancelled command finishes
+	closed    bool
+}
+
+// doContext sends the command in background, and returns ctx.Err() when ctx is done before the reply.
+func (c *contextConn) doContext(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.abandoned != nil {
+		c.mu.Unlock()
+		return nil, errAbandonedConn
+	}
+	c.mu.Unlock()
+
+	type result struct {
+		reply interface{}
+		err   error
+	}
+	ch := make(chan result, 1)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		reply, err := doDeadline(ctx, c.Conn, cmd, args...)
+		ch <- result{reply, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.reply, r.err
+	case <-ctx.Done():
+	}
+
+	c.mu.Lock()
+	c.abandoned = finished
+	c.mu.Unlock()
+	return nil, ctx.Err()
+}
+
+// Do sends the command, and fails after the command is cancelled.
+func (c *contextConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return c.Conn.Do(cmd, args...)
+}
+
+// DoWithTimeout sends the command with timeout, and fails after the command is cancelled.
+func (c *contextConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	if err := c.Err(); err != nil {
+		return nil, err
+	}
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+// ReceiveWithTimeout receives the reply with timeout.
+func (c *contextConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+// Err returns error after the command is cancelled.
+func (c *contextConn) Err() error {
+	c.mu.Lock()
+	abandoned := c.abandoned
+	c.mu.Unlock()
+	if abandoned != nil {
+		return errAbandonedConn
+	}
+	return c.Conn.Err()
+}
+
+// Close closes the connection, and waits the cancelled command in background.
+func (c *contextConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+
+	if c.abandoned == nil {
+		return c.Conn.Close()
+	}
+	go func(finished chan struct{}) {
+		<-finished
+		c.Conn.Close()
+	}(c.abandoned)
+	return nil
+}
+
+// doContext sends the command to redis and waits the reply until ctx is done.
+func doContext(ctx context.Context, conn redis.Conn, cmd string, args ...interface{}) (interface{}, error) {
+	if cc, ok := conn.(*contextConn); ok && ctx.Done() != nil {
+		return cc.doContext(ctx, cmd, args...)
+	}
+	return doDeadline(ctx, conn, cmd, args...)
+}
+
+// doDeadline sends the command to redis and waits the reply until the deadline of ctx
+func doDeadline(ctx context.Context, conn redis.Conn, cmd string, args ...interface{}) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return conn.Do(cmd, args...)
+	}
+
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+	return redis.DoWithTimeout(conn, timeout, cmd, args...)
+}
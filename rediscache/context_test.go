@@ -0,0 +1,60 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupContextCancel(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &slowConn{release: make(chan struct{})}
+	pool := &redis.Pool{
+		MaxIdle: 1,
+		Dial: func() (redis.Conn, error) {
+			return conn, nil
+		},
+	}
+	c := NewRedisCache(pool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	// the lookup returns without waiting the reply
+	start := time.Now()
+	var result string
+	ok, err := c.LookupContext(ctx, "key", &result)
+	assert.False(ok)
+	assert.True(errors.Is(err, context.Canceled))
+	assert.Less(time.Since(start), 500*time.Millisecond)
+	assert.Equal(1, pool.ActiveCount())
+
+	// the connection is returned to the pool after the reply
+	close(conn.release)
+	assert.Eventually(func() bool {
+		return pool.IdleCount() == 1
+	}, time.Second, time.Millisecond)
+}
+
+// slowConn is redis.Conn blocking GET until release is closed
+type slowConn struct {
+	redis.Conn
+	release chan struct{}
+}
+
+func (c *slowConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd == "GET" {
+		<-c.release
+		return nil, nil
+	}
+	return "OK", nil
+}
+
+func (c *slowConn) Err() error { return nil }
+
+func (c *slowConn) Close() error { return nil }
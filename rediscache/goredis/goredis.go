@@ -0,0 +1,188 @@
+// Package goredis provides rediscache.Client using go-redis.
+package goredis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	redigo "github.com/garyburd/redigo/redis"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/evalphobia/eurekache/rediscache"
+)
+
+// Client is rediscache.Client using redis.UniversalClient of go-redis
+type Client struct {
+	client redis.UniversalClient
+
+	// commands of multiple keys are split by key, since the keys may be stored in different nodes
+	multiNode bool
+}
+
+// NewClient returns initialized Client with given redis.UniversalClient
+// (*redis.Client, *redis.ClusterClient or *redis.Ring).
+// For *redis.ClusterClient and *redis.Ring, DEL and MGET of multiple keys are sent as the pipeline of each key.
+func NewClient(client redis.UniversalClient) *Client {
+	c := &Client{
+		client: client,
+	}
+	switch client.(type) {
+	case *redis.ClusterClient, *redis.Ring:
+		c.multiNode = true
+	}
+	return c
+}
+
+// NewRedisCache returns rediscache.RedisCache using given redis.UniversalClient
+func NewRedisCache(client redis.UniversalClient) *rediscache.RedisCache {
+	return rediscache.NewRedisCacheWithClient(NewClient(client))
+}
+
+// Get sends GET
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := c.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return b, convertError(err)
+}
+
+// Set sends SET
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return convertError(c.client.Set(ctx, key, value, ttl).Err())
+}
+
+// Del sends DEL
+func (c *Client) Del(ctx context.Context, keys ...string) error {
+	if c.multiNode && len(keys) > 1 {
+		return c.Pipeline(ctx, func(p rediscache.Pipeliner) {
+			p.Del(keys...)
+		})
+	}
+	return convertError(c.client.Del(ctx, keys...).Err())
+}
+
+// Expire sends EXPIRE or PEXPIRE
+func (c *Client) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return convertError(c.client.Expire(ctx, key, ttl).Err())
+}
+
+// MGet sends MGET, or GET of each key by pipeline for multiple nodes
+func (c *Client) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	if c.multiNode && len(keys) > 1 {
+		return c.getEach(ctx, keys)
+	}
+
+	values, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, convertError(err)
+	}
+
+	result := make([][]byte, len(values))
+	for i, v := range values {
+		if s, ok := v.(string); ok {
+			result[i] = []byte(s)
+		}
+	}
+	return result, nil
+}
+
+// getEach sends GET of each key by pipeline
+func (c *Client) getEach(ctx context.Context, keys []string) ([][]byte, error) {
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, err := c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = p.Get(ctx, key)
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, convertError(err)
+	}
+
+	result := make([][]byte, len(keys))
+	for i, cmd := range cmds {
+		b, err := cmd.Bytes()
+		switch {
+		case err == redis.Nil:
+		case err != nil:
+			return nil, convertError(err)
+		default:
+			result[i] = b
+		}
+	}
+	return result, nil
+}
+
+// Scan sends SCAN
+func (c *Client) Scan(ctx context.Context, cursor uint64, match string, count int64) ([]string, uint64, error) {
+	keys, cursor, err := c.client.Scan(ctx, cursor, match, count).Result()
+	return keys, cursor, convertError(err)
+}
+
+// Pipeline sends the commands by redis.Pipeliner
+func (c *Client) Pipeline(ctx context.Context, fn func(rediscache.Pipeliner)) error {
+	_, err := c.client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		fn(pipeliner{
+			ctx:       ctx,
+			pipe:      p,
+			multiNode: c.multiNode,
+		})
+		return nil
+	})
+	return convertError(err)
+}
+
+// Do sends the command
+func (c *Client) Do(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	v, err := c.client.Do(ctx, append([]interface{}{cmd}, args...)...).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return v, convertError(err)
+}
+
+// pipeliner queues commands into redis.Pipeliner
+type pipeliner struct {
+	ctx       context.Context
+	pipe      redis.Pipeliner
+	multiNode bool
+}
+
+// Set queues SET
+func (p pipeliner) Set(key string, value []byte, ttl time.Duration) {
+	if ttl < 0 {
+		ttl = 0
+	}
+	p.pipe.Set(p.ctx, key, value, ttl)
+}
+
+// Del queues DEL, or DEL of each key for multiple nodes
+func (p pipeliner) Del(keys ...string) {
+	if !p.multiNode {
+		p.pipe.Del(p.ctx, keys...)
+		return
+	}
+	for _, key := range keys {
+		p.pipe.Del(p.ctx, key)
+	}
+}
+
+// Expire queues EXPIRE or PEXPIRE
+func (p pipeliner) Expire(key string, ttl time.Duration) {
+	p.pipe.Expire(p.ctx, key, ttl)
+}
+
+// convertError converts error reply of go-redis into redis.Error of redigo,
+// so that rediscache handles error replies in the same way for both clients.
+func convertError(err error) error {
+	var replyErr redis.Error
+	if errors.As(err, &replyErr) {
+		return redigo.Error(replyErr.Error())
+	}
+	return err
+}
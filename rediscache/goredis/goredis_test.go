@@ -0,0 +1,130 @@
+package goredis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	redigo "github.com/garyburd/redigo/redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/evalphobia/eurekache/rediscache"
+)
+
+var testRedisHost = "127.0.0.1:6379"
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	rc := redis.NewClient(&redis.Options{Addr: testRedisHost})
+	defer rc.Close()
+	c := NewClient(rc)
+
+	key := "eurekache:goredis:key"
+	assert.NoError(c.Set(ctx, key, []byte("value"), time.Minute))
+	b, err := c.Get(ctx, key)
+	assert.NoError(err)
+	assert.Equal([]byte("value"), b)
+
+	assert.NoError(c.Expire(ctx, key, 10*time.Second))
+	ttl, err := rc.TTL(ctx, key).Result()
+	assert.NoError(err)
+	assert.True(ttl <= 10*time.Second)
+
+	values, err := c.MGet(ctx, key, "eurekache:goredis:nokey")
+	assert.NoError(err)
+	assert.Equal([][]byte{[]byte("value"), nil}, values)
+
+	keys, _, err := c.Scan(ctx, 0, "eurekache:goredis:*", 100)
+	assert.NoError(err)
+	assert.Contains(keys, key)
+
+	assert.NoError(c.Del(ctx, key))
+	b, err = c.Get(ctx, key)
+	assert.NoError(err)
+	assert.Nil(b)
+
+	// pipeline
+	assert.NoError(c.Pipeline(ctx, func(p rediscache.Pipeliner) {
+		p.Set(key, []byte("value1"), 0)
+		p.Expire(key, time.Minute)
+	}))
+	b, _ = c.Get(ctx, key)
+	assert.Equal([]byte("value1"), b)
+
+	// error reply
+	_, err = c.Do(ctx, "INCR", key)
+	assert.IsType(redigo.Error(""), err)
+	v, err := c.Do(ctx, "GET", "eurekache:goredis:nokey")
+	assert.NoError(err)
+	assert.Nil(v)
+}
+
+func TestNewRedisCache(t *testing.T) {
+	assert := assert.New(t)
+
+	rc := redis.NewClient(&redis.Options{Addr: testRedisHost})
+	defer rc.Close()
+	c := NewRedisCache(rc)
+	c.SetPrefix("eurekache:goredis:")
+	c.SetTTL(10000)
+
+	assert.NoError(c.Ping(context.Background()))
+	assert.NoError(c.SetWithTags("key", "value", 10000, "tag"))
+
+	var v string
+	assert.True(c.Get("key", &v))
+	assert.Equal("value", v)
+
+	assert.NoError(c.InvalidateTag("tag"))
+	assert.False(c.Get("key", &v))
+}
+
+func TestClientMultiNode(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	// two shards on the different db of the same server
+	ring := redis.NewRing(&redis.RingOptions{
+		Addrs: map[string]string{
+			"shard1": testRedisHost,
+			"shard2": "localhost" + testRedisHost[strings.Index(testRedisHost, ":"):],
+		},
+		NewClient: func(opt *redis.Options) *redis.Client {
+			if strings.HasPrefix(opt.Addr, "localhost") {
+				opt.DB = 1
+			}
+			return redis.NewClient(opt)
+		},
+	})
+	defer ring.Close()
+	c := NewClient(ring)
+	assert.True(c.multiNode)
+
+	keys := make([]string, 10)
+	for i := range keys {
+		keys[i] = "eurekache:goredis:multi" + strconv.Itoa(i)
+		assert.NoError(c.Set(ctx, keys[i], []byte(keys[i]), time.Minute))
+	}
+
+	values, err := c.MGet(ctx, append(keys, "eurekache:goredis:nokey")...)
+	assert.NoError(err)
+	assert.Len(values, len(keys)+1)
+	for i, key := range keys {
+		assert.Equal([]byte(key), values[i])
+	}
+	assert.Nil(values[len(keys)])
+
+	assert.NoError(c.Del(ctx, keys...))
+	for _, key := range keys {
+		b, err := c.Get(ctx, key)
+		assert.NoError(err)
+		assert.Nil(b)
+	}
+
+	assert.False(NewClient(redis.NewClient(&redis.Options{Addr: testRedisHost})).multiNode)
+}
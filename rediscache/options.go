@@ -0,0 +1,205 @@
+package rediscache
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+const (
+	defaultRedisAddr   = "127.0.0.1:6379"
+	defaultMaxIdle     = 10
+	defaultIdleTimeout = 240 * time.Second
+)
+
+var errInvalidURL = errors.New("invalid redis url")
+
+// Options is options to create RedisCache with redis.Pool
+type Options struct {
+	// Addr is host:port of redis-server. (default: 127.0.0.1:6379)
+	Addr string
+	// Username is ACL user of Redis 6 or later. AUTH is sent with only password when it's empty.
+	Username string
+	// Password is sent by AUTH when it's not empty.
+	Password string
+	DB       int
+
+	// TLSConfig enables TLS when it's not nil.
+	TLSConfig *tls.Config
+
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// MaxIdle is max number of idle connections in the pool. (default: 10)
+	MaxIdle int
+	// MaxActive is max number of connections in the pool, and zero means no limit.
+	MaxActive int
+	// IdleTimeout closes idle connections after the duration. (default: 240s)
+	IdleTimeout time.Duration
+	// Wait waits for a connection to be returned into the pool when MaxActive is reached.
+	Wait bool
+
+	Prefix string
+	TTL    time.Duration
+}
+
+// NewRedisCacheWithOptions returns initialized RedisCache with redis.Pool created by the options
+func NewRedisCacheWithOptions(opt Options) *RedisCache {
+	c := NewRedisCache(opt.pool())
+	c.Select(opt.DB)
+	c.SetPrefix(opt.Prefix)
+	c.SetTTL(opt.TTL.Milliseconds())
+	return c
+}
+
+// NewRedisCacheFromURL returns initialized RedisCache with the options parsed from the url.
+//
+//	redis://[[user]:password@]host[:port][/db][?option=value]
+//	rediss://... (TLS)
+//
+// Available options are prefix, ttl, dial_timeout, read_timeout, write_timeout,
+// max_idle, max_active, idle_timeout, wait and skip_verify (rediss only).
+func NewRedisCacheFromURL(rawurl string) (*RedisCache, error) {
+	opt, err := ParseURL(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return NewRedisCacheWithOptions(opt), nil
+}
+
+// ParseURL parses redis url into Options.
+// See NewRedisCacheFromURL for the format.
+func ParseURL(rawurl string) (Options, error) {
+	var opt Options
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return opt, err
+	}
+
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		opt.TLSConfig = &tls.Config{}
+	default:
+		return opt, fmt.Errorf("%w: unknown scheme %q", errInvalidURL, u.Scheme)
+	}
+
+	opt.Addr = u.Host
+	if opt.Addr == "" {
+		opt.Addr = defaultRedisAddr
+	} else if u.Port() == "" {
+		opt.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	if u.User != nil {
+		opt.Username = u.User.Username()
+		opt.Password, _ = u.User.Password()
+	}
+
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		opt.DB, err = strconv.Atoi(db)
+		if err != nil {
+			return opt, fmt.Errorf("%w: invalid db %q", errInvalidURL, db)
+		}
+	}
+
+	for name, values := range u.Query() {
+		err = opt.setQuery(name, values[len(values)-1])
+		if err != nil {
+			return opt, fmt.Errorf("%w: invalid %s: %s", errInvalidURL, name, err.Error())
+		}
+	}
+	return opt, nil
+}
+
+// setQuery sets the option of the query parameter
+func (opt *Options) setQuery(name, value string) (err error) {
+	switch name {
+	case "prefix":
+		opt.Prefix = value
+	case "ttl":
+		opt.TTL, err = time.ParseDuration(value)
+	case "dial_timeout":
+		opt.DialTimeout, err = time.ParseDuration(value)
+	case "read_timeout":
+		opt.ReadTimeout, err = time.ParseDuration(value)
+	case "write_timeout":
+		opt.WriteTimeout, err = time.ParseDuration(value)
+	case "max_idle":
+		opt.MaxIdle, err = strconv.Atoi(value)
+	case "max_active":
+		opt.MaxActive, err = strconv.Atoi(value)
+	case "idle_timeout":
+		opt.IdleTimeout, err = time.ParseDuration(value)
+	case "wait":
+		opt.Wait, err = strconv.ParseBool(value)
+	case "skip_verify":
+		if opt.TLSConfig == nil {
+			return errors.New("TLS is not enabled")
+		}
+		opt.TLSConfig.InsecureSkipVerify, err = strconv.ParseBool(value)
+	default:
+		return errors.New("unknown option")
+	}
+	return err
+}
+
+// pool returns redis.Pool created by the options
+func (opt Options) pool() *redis.Pool {
+	maxIdle := opt.MaxIdle
+	if maxIdle == 0 {
+		maxIdle = defaultMaxIdle
+	}
+	idleTimeout := opt.IdleTimeout
+	if idleTimeout == 0 {
+		idleTimeout = defaultIdleTimeout
+	}
+
+	return &redis.Pool{
+		MaxIdle:     maxIdle,
+		MaxActive:   opt.MaxActive,
+		IdleTimeout: idleTimeout,
+		Wait:        opt.Wait,
+		Dial:        TrackSelect(opt.dial),
+	}
+}
+
+// dial connects to redis-server and sends AUTH
+func (opt Options) dial() (redis.Conn, error) {
+	addr := opt.Addr
+	if addr == "" {
+		addr = defaultRedisAddr
+	}
+
+	conn, err := redis.Dial("tcp", addr,
+		redis.DialConnectTimeout(opt.DialTimeout),
+		redis.DialReadTimeout(opt.ReadTimeout),
+		redis.DialWriteTimeout(opt.WriteTimeout),
+		redis.DialUseTLS(opt.TLSConfig != nil),
+		redis.DialTLSConfig(opt.TLSConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case opt.Password == "":
+	case opt.Username != "":
+		_, err = conn.Do("AUTH", opt.Username, opt.Password)
+	default:
+		_, err = conn.Do("AUTH", opt.Password)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
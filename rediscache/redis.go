@@ -1,47 +1,111 @@
 package rediscache
 
 import (
-	"bytes"
-	"encoding/gob"
+	"context"
 	"errors"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/evalphobia/eurekache"
 	"github.com/garyburd/redigo/redis"
 )
 
+const (
+	tagKeyPrefix = "__tag:"
+	scanCount    = 100
+)
+
 var (
 	errNilPool    = errors.New("redis.Pool is nil")
 	errClosedConn = errors.New("redis.Conn is closed")
 )
 
-// RedisCache is a cache source for Redis and contains redis.Pool
+// RedisCache is a cache source for Redis and contains redis.Pool or Client
 type RedisCache struct {
 	pool       *redis.Pool
+	client     Client
 	dbno       string
 	prefix     string
 	defaultTTL int64
+	jitter     eurekache.Jitter
+	name       string
+
+	// for client side caching
+	trackingMu sync.Mutex
+	tracker    atomic.Pointer[tracker]
+
+	retryPolicy RetryPolicy
+
+	// for sentinel
+	sentinel *Sentinel
+	readPool *redis.Pool
 }
 
-// NewRedisCache returns initialized RedisCache with given redis.Pool
+// NewRedisCache returns initialized RedisCache with given redis.Pool.
+// SELECT is sent only when the connection uses another db if Dial of the pool is wrapped by TrackSelect,
+// otherwise it's sent before every command.
 func NewRedisCache(pool *redis.Pool) *RedisCache {
 	return &RedisCache{
 		pool: pool,
 		dbno: "0",
+		name: "redis",
+	}
+}
+
+// NewRedisCacheWithClient returns initialized RedisCache with given Client.
+// Select, EnableTracking and SetReadFromReplicas are not supported, configure the client instead.
+func NewRedisCacheWithClient(client Client) *RedisCache {
+	return &RedisCache{
+		client: client,
+		dbno:   "0",
+		name:   "redis",
 	}
 }
 
+// SetName sets the name of the cache source
+func (c *RedisCache) SetName(name string) {
+	c.name = name
+}
+
+// Name returns the name of the cache source
+func (c *RedisCache) Name() string {
+	return c.name
+}
+
+// Ping checks the connection to redis until the deadline of ctx
+func (c *RedisCache) Ping(ctx context.Context) error {
+	client := c.client
+	if client == nil {
+		// the connection of client side caching is not used
+		client = redigoClient{conn: c.connContext}
+	}
+	_, err := client.Do(ctx, "PING")
+	if err != nil {
+		return wrapConnError(err)
+	}
+	return nil
+}
+
 // SetTTL sets default TTL (milliseconds)
 func (c *RedisCache) SetTTL(ttl int64) {
 	c.defaultTTL = ttl
 }
 
+// SetJitter sets jitter for TTL
+func (c *RedisCache) SetJitter(j eurekache.Jitter) {
+	c.jitter = j
+}
+
 // SetPrefix sets the prefix used for adding prefix into key name
 func (c *RedisCache) SetPrefix(prefix string) {
 	c.prefix = prefix
 }
 
-// Select sets db number for redis-server
+// Select sets db number for redis-server.
+// It's ignored when the cache is created by NewRedisCacheWithClient.
 func (c *RedisCache) Select(num int) {
 	c.dbno = strconv.Itoa(num)
 }
@@ -49,14 +113,39 @@ func (c *RedisCache) Select(num int) {
 // Get searches cache by given key from redis and returns flag of cache is existed or not.
 // when cache hit, data is assigned.
 func (c *RedisCache) Get(key string, data interface{}) bool {
-	b, ok := c.GetGobBytes(key)
-	if !ok {
-		return false
+	ok, _ := c.Lookup(key, data)
+	return ok
+}
+
+// Lookup searches cache by given key from redis and returns flag of cache is existed or not, and error.
+// when cache hit, data is assigned.
+func (c *RedisCache) Lookup(key string, data interface{}) (bool, error) {
+	_, ok, err := c.LookupItem(context.Background(), key, data)
+	return ok, err
+}
+
+// LookupContext searches cache like Lookup, and stops waiting the reply of redis when ctx is done.
+func (c *RedisCache) LookupContext(ctx context.Context, key string, data interface{}) (bool, error) {
+	_, ok, err := c.LookupItem(ctx, key, data)
+	return ok, err
+}
+
+// LookupItem searches cache by given key from redis and returns Item and error.
+// when cache hit, data is assigned.
+func (c *RedisCache) LookupItem(ctx context.Context, key string, data interface{}) (*eurekache.Item, bool, error) {
+	item, ok, err := c.getItem(ctx, key)
+	switch {
+	case !ok:
+		return nil, false, err
+	case item.Value == nil:
+		return nil, false, nil
 	}
 
-	dec := gob.NewDecoder(bytes.NewBuffer(b))
-	dec.Decode(data)
-	return true
+	err = item.Assign(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return item, true, nil
 }
 
 // GetInterface searches cache by given key from redis and returns interface value.
@@ -72,49 +161,46 @@ func (c *RedisCache) GetInterface(key string) (interface{}, bool) {
 // GetGobBytes searches cache by given key from redis and returns gob-encoded value.
 func (c *RedisCache) GetGobBytes(key string) ([]byte, bool) {
 	item, ok := c.getGobItem(key)
-	switch {
-	case !ok:
-		return nil, false
-	case item.Value == nil:
-		return nil, false
-	}
-
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err := enc.Encode(item.Value)
-	if err != nil {
+	if !ok {
 		return nil, false
 	}
-
-	return buf.Bytes(), true
+	return item.GobBytes()
 }
 
 // getGobItem searches cache by given key from redis and returns Item data
 func (c *RedisCache) getGobItem(key string) (*eurekache.Item, bool) {
-	conn, err := c.conn()
-	if err != nil {
-		return nil, false
-	}
-	defer conn.Close()
+	item, ok, _ := c.getItem(context.Background(), key)
+	return item, ok
+}
 
-	data, err := conn.Do("GET", c.prefix+key)
-	if err != nil {
-		return nil, false
-	}
+// getItem searches cache by given key from redis and returns Item data and error.
+// failed GET is retried by the retry policy.
+func (c *RedisCache) getItem(ctx context.Context, key string) (*eurekache.Item, bool, error) {
+	var item *eurekache.Item
+	var ok bool
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		item, ok, err = c.getItemOnce(ctx, key)
+		return err
+	})
+	return item, ok, err
+}
 
-	b, err := redis.Bytes(data, err)
-	if err != nil {
-		return nil, false
+// getItemOnce sends GET to redis and returns Item data and error
+func (c *RedisCache) getItemOnce(ctx context.Context, key string) (*eurekache.Item, bool, error) {
+	b, err := c.reader().Get(ctx, c.prefix+key)
+	switch {
+	case err != nil:
+		return nil, false, wrapConnError(err)
+	case b == nil:
+		return nil, false, nil
 	}
 
-	var item eurekache.Item
-	dec := gob.NewDecoder(bytes.NewBuffer(b))
-	err = dec.Decode(&item)
+	item, err := eurekache.DecodeItem(b)
 	if err != nil {
-		return nil, false
+		return nil, false, err
 	}
-
-	return &item, true
+	return item, true, nil
 }
 
 // Set sets data into redis. data is wrapped by gob-encoded Item
@@ -124,40 +210,233 @@ func (c *RedisCache) Set(key string, data interface{}) error {
 
 // SetExpire sets data into redis with TTL. data is wrapped by gob-encoded Item
 func (c *RedisCache) SetExpire(key string, data interface{}, ttl int64) error {
-	conn, err := c.conn()
+	return c.SetWithTags(key, data, ttl)
+}
+
+// SetWithTags sets data into redis with TTL and tags. data is wrapped by gob-encoded Item
+// Each tag is stored as a set of keys, and the set lives at least as long as its keys.
+// failed operation is retried by the retry policy.
+func (c *RedisCache) SetWithTags(key string, data interface{}, ttl int64, tags ...string) error {
+	return c.retry(context.Background(), func(ctx context.Context) error {
+		return c.setWithTags(ctx, key, data, ttl, tags...)
+	})
+}
+
+// setWithTags sends SET or DEL and tag commands to redis
+func (c *RedisCache) setWithTags(ctx context.Context, key string, data interface{}, ttl int64, tags ...string) error {
+	client := c.writer()
+	if data == nil {
+		return client.Del(ctx, c.prefix+key)
+	}
+
+	ttl = c.jitter.Apply(ttl)
+	b, err := eurekache.EncodeItem(data, ttl)
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
 
-	if data == nil {
-		_, err = conn.Do("DEL", c.prefix+key)
+	err = client.Set(ctx, c.prefix+key, b, msDuration(ttl))
+	if err != nil {
+		return err
+	}
+
+	do := func(cmd string, args ...interface{}) (interface{}, error) {
+		return client.Do(ctx, cmd, args...)
+	}
+	for _, tag := range tags {
+		err = addTag(do, c.prefix+tagKeyPrefix+tag, key, ceilSeconds(ttl))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetMulti sets multiple data into redis at once using pipeline.
+// Data with tags is set by SetWithTags after the pipeline.
+func (c *RedisCache) SetMulti(entries []eurekache.WriteEntry) error {
+	var tagged []eurekache.WriteEntry
+	var values []multiValue
+	for _, e := range entries {
+		if len(e.Tags) != 0 {
+			tagged = append(tagged, e)
+			continue
+		}
+
+		v := multiValue{key: c.prefix + e.Key}
+		if e.Value != nil {
+			ttl := e.TTL
+			if e.UseDefaultTTL {
+				ttl = c.defaultTTL
+			}
+			ttl = c.jitter.Apply(ttl)
+
+			var err error
+			v.value, err = eurekache.EncodeItem(e.Value, ttl)
+			if err != nil {
+				return err
+			}
+			v.ttl = msDuration(ttl)
+		}
+		values = append(values, v)
+	}
+
+	if len(values) != 0 {
+		err := c.setValues(values)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, e := range tagged {
+		err := c.SetWithTags(e.Key, e.Value, e.TTL, e.Tags...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// multiValue is encoded data set by SetMulti, and nil value is deleted
+type multiValue struct {
+	key   string
+	value []byte
+	ttl   time.Duration
+}
+
+// setValues sends SET or DEL of the values at once by pipeline.
+// failed pipeline is retried by the retry policy.
+func (c *RedisCache) setValues(values []multiValue) error {
+	return c.retry(context.Background(), func(ctx context.Context) error {
+		return c.writer().Pipeline(ctx, func(p Pipeliner) {
+			for _, v := range values {
+				if v.value == nil {
+					p.Del(v.key)
+					continue
+				}
+				p.Set(v.key, v.value, v.ttl)
+			}
+		})
+	})
+}
+
+// GetMulti searches multiple cache by given keys from redis at once, and returns values of the keys cache hit.
+// Data failed to decode is regarded as cache miss.
+func (c *RedisCache) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+
+	var values [][]byte
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		values, err = c.reader().MGet(ctx, prefixed...)
 		return err
+	})
+	if err != nil {
+		return nil, wrapConnError(err)
+	}
+
+	result := make(map[string]interface{}, len(values))
+	for i, b := range values {
+		if b == nil || i >= len(keys) {
+			continue
+		}
+		item, err := eurekache.DecodeItem(b)
+		if err != nil || item.Value == nil {
+			continue
+		}
+		result[keys[i]] = item.Value
+	}
+	return result, nil
+}
+
+// Keys returns the keys of cached data matching the pattern by SCAN, without the prefix.
+func (c *RedisCache) Keys(ctx context.Context, pattern string) ([]string, error) {
+	client := c.reader()
+	tagPrefix := c.prefix + tagKeyPrefix
+
+	var result []string
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, c.prefix+pattern, scanCount)
+		if err != nil {
+			return nil, wrapConnError(err)
+		}
+		for _, key := range keys {
+			if strings.HasPrefix(key, tagPrefix) {
+				continue
+			}
+			result = append(result, strings.TrimPrefix(key, c.prefix))
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return result, nil
+		}
+	}
+}
+
+// msDuration converts TTL in milliseconds into time.Duration
+func msDuration(ttl int64) time.Duration {
+	return time.Duration(ttl) * time.Millisecond
+}
+
+// ceilSeconds converts TTL (milliseconds) into seconds, and TTL less than a second is rounded up to a second.
+func ceilSeconds(ttl int64) int64 {
+	if ttl < 1 {
+		return 0
 	}
+	return (ttl + 999) / 1000
+}
 
-	item := eurekache.NewItem()
-	item.SetExpire(ttl)
-	item.Value = data
+// addTag adds key into the set of tag and extends TTL (sec) of the set.
+// do sends the command to the node having tagKey.
+func addTag(do func(string, ...interface{}) (interface{}, error), tagKey, key string, ttl int64) error {
+	current, err := redis.Int64(do("TTL", tagKey))
+	if err != nil {
+		return err
+	}
 
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err = enc.Encode(item)
+	_, err = do("SADD", tagKey, key)
 	if err != nil {
 		return err
 	}
 
 	switch {
 	case ttl < 1:
-		_, err = conn.Do("SET", c.prefix+key, buf.Bytes())
-	default:
-		ttl = ttl / 1000 // convert milli-sec to sec
-		_, err = conn.Do("SETEX", c.prefix+key, ttl, buf.Bytes())
+		_, err = do("PERSIST", tagKey)
+	case current == -2, // new set
+		current >= 0 && current < ttl:
+		_, err = do("EXPIRE", tagKey, ttl)
 	}
+	return err
+}
+
+// InvalidateTag deletes all of cached data related to the tag from redis.
+// failed operation is retried by the retry policy.
+func (c *RedisCache) InvalidateTag(tag string) error {
+	return c.retry(context.Background(), func(ctx context.Context) error {
+		return c.invalidateTag(ctx, tag)
+	})
+}
 
+// invalidateTag sends SMEMBERS and DEL to redis
+func (c *RedisCache) invalidateTag(ctx context.Context, tag string) error {
+	client := c.writer()
+	tagKey := c.prefix + tagKeyPrefix + tag
+	keys, err := redis.Strings(client.Do(ctx, "SMEMBERS", tagKey))
 	if err != nil {
 		return err
 	}
-	return nil
+
+	delKeys := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		delKeys = append(delKeys, c.prefix+key)
+	}
+	delKeys = append(delKeys, tagKey)
+	return client.Del(ctx, delKeys...)
 }
 
 // Clear does nothing on RedisCache.
@@ -165,20 +444,65 @@ func (c *RedisCache) Clear() error {
 	return nil
 }
 
+// wrapConnError wraps error from redis connection with eurekache.ErrTimeout or eurekache.ErrConnection.
+// error reply from redis-server is returned as it is.
+func wrapConnError(err error) error {
+	if _, ok := err.(redis.Error); ok {
+		return err
+	}
+
+	return eurekache.WrapConnError(err)
+}
+
+// reader returns Client for read operations
+func (c *RedisCache) reader() Client {
+	if c.client != nil {
+		return c.client
+	}
+	return redigoClient{conn: c.readConnContext}
+}
+
+// writer returns Client for write operations
+func (c *RedisCache) writer() Client {
+	if c.client != nil {
+		return c.client
+	}
+	return redigoClient{conn: c.writeConnContext}
+}
+
 // conn returns redis.Conn created from redis.Pool
 func (c *RedisCache) conn() (redis.Conn, error) {
-	if c.pool == nil {
+	return c.connContext(context.Background())
+}
+
+// connContext returns redis.Conn created from redis.Pool until the deadline of ctx
+func (c *RedisCache) connContext(ctx context.Context) (redis.Conn, error) {
+	return c.poolConn(ctx, c.pool)
+}
+
+// readConnContext returns redis.Conn for read operations until the deadline of ctx
+func (c *RedisCache) readConnContext(ctx context.Context) (redis.Conn, error) {
+	if c.readPool != nil {
+		return c.poolConn(ctx, c.readPool)
+	}
+	return c.connContext(ctx)
+}
+
+// poolConn returns redis.Conn created from the pool and selects the db
+func (c *RedisCache) poolConn(ctx context.Context, pool *redis.Pool) (redis.Conn, error) {
+	if pool == nil {
 		return nil, errNilPool
 	}
 
-	conn := c.pool.Get()
-	err := conn.Err()
+	conn, err := pool.GetContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	conn = newContextConn(conn)
 
-	_, err = conn.Do("SELECT", c.dbno)
+	_, err = doContext(ctx, conn, "SELECT", c.dbno)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 
@@ -2,7 +2,9 @@ package rediscache
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
+	"errors"
 	"testing"
 	"time"
 
@@ -37,6 +39,43 @@ func TestSetTTL(t *testing.T) {
 	assert.EqualValues(c.defaultTTL, 100)
 }
 
+func TestSetJitter(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewRedisCache(nil)
+	assert.Equal(eurekache.Jitter{}, c.jitter)
+
+	j := eurekache.Jitter{Percent: 0.1, Range: 1000}
+	c.SetJitter(j)
+	assert.Equal(j, c.jitter)
+}
+
+func TestName(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewRedisCache(nil)
+	assert.Equal("redis", c.Name())
+
+	c.SetName("remote")
+	assert.Equal("remote", c.Name())
+}
+
+func TestPing(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewRedisCache(helper.TestGetPool())
+	assert.NoError(c.Ping(context.Background()))
+
+	// connection failure
+	c = NewRedisCache(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", "127.0.0.1:0")
+		},
+	})
+	err := c.Ping(context.Background())
+	assert.True(errors.Is(err, eurekache.ErrConnection))
+}
+
 func TestSetPrefix(t *testing.T) {
 	assert := assert.New(t)
 
@@ -86,6 +125,80 @@ func TestGet(t *testing.T) {
 	assert.Empty(result2)
 }
 
+func TestLookup(t *testing.T) {
+	assert := assert.New(t)
+	key := "keyTestLookup"
+
+	pool := helper.TestGetPool()
+	c := NewRedisCache(pool)
+	c.SetPrefix(testRedisPrefix)
+
+	var result string
+	var ok bool
+	var err error
+
+	// miss cache
+	ok, err = c.Lookup("nokey", &result)
+	assert.False(ok)
+	assert.NoError(err)
+
+	// hit cache
+	c.Set(key, "valueTestLookup")
+	ok, err = c.Lookup(key, &result)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("valueTestLookup", result)
+
+	// value decode failure
+	var resultInt int
+	ok, err = c.Lookup(key, &resultInt)
+	assert.False(ok)
+	assert.True(errors.Is(err, eurekache.ErrDecode))
+	assert.False(c.Get(key, &resultInt))
+
+	// item decode failure
+	pool.Get().Do("SET", testRedisPrefix+key, "invalid data")
+	ok, err = c.Lookup(key, &result)
+	assert.False(ok)
+	assert.True(errors.Is(err, eurekache.ErrDecode))
+
+	// connection failure
+	c = NewRedisCache(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", "127.0.0.1:0")
+		},
+	})
+	ok, err = c.Lookup(key, &result)
+	assert.False(ok)
+	assert.True(errors.Is(err, eurekache.ErrConnection))
+}
+
+func TestLookupContext(t *testing.T) {
+	assert := assert.New(t)
+	key := "keyTestLookupContext"
+
+	c := NewRedisCache(helper.TestGetPool())
+	c.SetPrefix(testRedisPrefix)
+	c.Set(key, "valueTestLookupContext")
+
+	var result string
+
+	// hit cache
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ok, err := c.LookupContext(ctx, key, &result)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("valueTestLookupContext", result)
+
+	// deadline exceeded
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	ok, err = c.LookupContext(ctx, key, &result)
+	assert.False(ok)
+	assert.True(errors.Is(err, eurekache.ErrTimeout))
+}
+
 func TestGetInterface(t *testing.T) {
 	assert := assert.New(t)
 	key := "key"
@@ -192,6 +305,155 @@ func TestSetExpire(t *testing.T) {
 	assert.False(ok)
 }
 
+func TestSetWithTags(t *testing.T) {
+	assert := assert.New(t)
+	key := "keyTestSetWithTags"
+
+	pool := helper.TestGetPool()
+	c := NewRedisCache(pool)
+	c.SetPrefix(testRedisPrefix)
+
+	err := c.SetWithTags(key, "valueTestSetWithTags", 10000, "tag1", "tag2")
+	assert.NoError(err)
+
+	var v string
+	ok := c.Get(key, &v)
+	assert.True(ok)
+	assert.Equal("valueTestSetWithTags", v)
+
+	// check tag set
+	keys, err := redis.Strings(pool.Get().Do("SMEMBERS", testRedisPrefix+tagKeyPrefix+"tag1"))
+	assert.NoError(err)
+	assert.Contains(keys, key)
+
+	ttl, err := redis.Int64(pool.Get().Do("TTL", testRedisPrefix+tagKeyPrefix+"tag1"))
+	assert.NoError(err)
+	assert.True(ttl > 0)
+	assert.True(ttl <= 10)
+
+	// sub-second TTL is rounded up to a second
+	pool.Get().Do("DEL", testRedisPrefix+tagKeyPrefix+"tagSubSecond")
+	err = c.SetWithTags(key, "valueTestSetWithTags", 500, "tagSubSecond")
+	assert.NoError(err)
+	ttl, err = redis.Int64(pool.Get().Do("TTL", testRedisPrefix+tagKeyPrefix+"tagSubSecond"))
+	assert.NoError(err)
+	assert.EqualValues(1, ttl)
+}
+
+func TestCeilSeconds(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.EqualValues(0, ceilSeconds(0))
+	assert.EqualValues(0, ceilSeconds(-1))
+	assert.EqualValues(1, ceilSeconds(1))
+	assert.EqualValues(1, ceilSeconds(1000))
+	assert.EqualValues(2, ceilSeconds(1001))
+}
+
+func TestSetMulti(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := helper.TestGetPool()
+	c := NewRedisCache(pool)
+	c.SetPrefix(testRedisPrefix)
+	c.SetTTL(10000)
+	c.Set("keyTestSetMultiDel", "value")
+
+	err := c.SetMulti([]eurekache.WriteEntry{
+		{Key: "keyTestSetMulti1", Value: "value1", UseDefaultTTL: true},
+		{Key: "keyTestSetMulti2", Value: "value2", TTL: 20000},
+		{Key: "keyTestSetMulti3", Value: "value3", TTL: 10000, Tags: []string{"tagTestSetMulti"}},
+		{Key: "keyTestSetMultiDel", Value: nil},
+	})
+	assert.NoError(err)
+
+	var v string
+	assert.True(c.Get("keyTestSetMulti1", &v))
+	assert.Equal("value1", v)
+	assert.True(c.Get("keyTestSetMulti2", &v))
+	assert.Equal("value2", v)
+	assert.True(c.Get("keyTestSetMulti3", &v))
+	assert.Equal("value3", v)
+	assert.False(c.Get("keyTestSetMultiDel", &v))
+
+	ttl, err := redis.Int64(pool.Get().Do("TTL", testRedisPrefix+"keyTestSetMulti2"))
+	assert.NoError(err)
+	assert.True(ttl > 10)
+	assert.True(ttl <= 20)
+
+	keys, err := redis.Strings(pool.Get().Do("SMEMBERS", testRedisPrefix+tagKeyPrefix+"tagTestSetMulti"))
+	assert.NoError(err)
+	assert.Contains(keys, "keyTestSetMulti3")
+}
+
+func TestGetMulti(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := helper.TestGetPool()
+	c := NewRedisCache(pool)
+	c.SetPrefix(testRedisPrefix)
+	c.Set("keyTestGetMulti1", "value1")
+	c.Set("keyTestGetMulti2", 2)
+	pool.Get().Do("SET", testRedisPrefix+"keyTestGetMultiBroken", "broken")
+
+	values, err := c.GetMulti(context.Background(), []string{
+		"keyTestGetMulti1",
+		"keyTestGetMulti2",
+		"keyTestGetMultiBroken",
+		"keyTestGetMultiNothing",
+	})
+	assert.NoError(err)
+	assert.Equal(map[string]interface{}{
+		"keyTestGetMulti1": "value1",
+		"keyTestGetMulti2": 2,
+	}, values)
+}
+
+func TestKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewRedisCache(helper.TestGetPool())
+	c.SetPrefix(testRedisPrefix)
+	c.SetWithTags("keyTestKeys1", "value1", 10000, "keyTestKeysTag")
+	c.Set("keyTestKeys2", "value2")
+
+	keys, err := c.Keys(context.Background(), "keyTestKeys*")
+	assert.NoError(err)
+	assert.ElementsMatch([]string{"keyTestKeys1", "keyTestKeys2"}, keys)
+
+	keys, err = c.Keys(context.Background(), "*keyTestKeysTag")
+	assert.NoError(err)
+	assert.Empty(keys)
+}
+
+func TestInvalidateTag(t *testing.T) {
+	assert := assert.New(t)
+	key1 := "key1TestInvalidateTag"
+	key2 := "key2TestInvalidateTag"
+
+	pool := helper.TestGetPool()
+	c := NewRedisCache(pool)
+	c.SetPrefix(testRedisPrefix)
+
+	c.SetWithTags(key1, "value1", 0, "tagInvalidate1")
+	c.SetWithTags(key2, "value2", 0, "tagInvalidate2")
+
+	err := c.InvalidateTag("tagInvalidate1")
+	assert.NoError(err)
+
+	var v string
+	assert.False(c.Get(key1, &v))
+	assert.True(c.Get(key2, &v))
+
+	exists, err := redis.Bool(pool.Get().Do("EXISTS", testRedisPrefix+tagKeyPrefix+"tagInvalidate1"))
+	assert.NoError(err)
+	assert.False(exists)
+
+	// unknown tag
+	err = c.InvalidateTag("tag_unknown")
+	assert.NoError(err)
+}
+
 func TestConn(t *testing.T) {
 	assert := assert.New(t)
 
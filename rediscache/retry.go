@@ -0,0 +1,139 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/evalphobia/eurekache"
+	"github.com/garyburd/redigo/redis"
+)
+
+// error replies from redis-server which may succeed on retry
+var retryableReplies = []string{
+	"LOADING",
+	"BUSY",
+	"TRYAGAIN",
+	"CLUSTERDOWN",
+	"MASTERDOWN",
+	"READONLY",
+}
+
+// RetryPolicy is a policy for retrying redis operations
+type RetryPolicy struct {
+	// max number of attempts including the first one. retry is disabled when it's less than 2.
+	MaxAttempts int
+
+	// waiting time before the first retry, and it's doubled on each retry up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// ratio of randomized waiting time (0.0 - 1.0).
+	// e.g.) when Jitter=0.5 and the delay is 100ms, actual delay is 50ms - 100ms.
+	Jitter float64
+
+	// time limit for all of attempts. 0 means no limit.
+	Deadline time.Duration
+
+	// classifies the error is retryable or not. IsRetryable is used when it's nil.
+	Retryable func(error) bool
+}
+
+// SetRetryPolicy sets policy for retrying GET, SET, DEL and batch operations
+func (c *RedisCache) SetRetryPolicy(p RetryPolicy) {
+	c.retryPolicy = p
+}
+
+// IsRetryable checks the error is transient failure or not.
+// Network errors, timeout, exhausted pool and some error replies (e.g. LOADING) are retryable.
+func IsRetryable(err error) bool {
+	var redisErr redis.Error
+	switch {
+	case err == nil,
+		err == redis.ErrNil,
+		errors.Is(err, errNilPool),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, eurekache.ErrDecode):
+		return false
+	case errors.As(err, &redisErr):
+		for _, prefix := range retryableReplies {
+			if strings.HasPrefix(string(redisErr), prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, eurekache.ErrTimeout),
+		errors.Is(err, eurekache.ErrConnection),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, redis.ErrPoolExhausted),
+		errors.As(err, &netErr):
+		return true
+	}
+	return false
+}
+
+// retry runs fn by the retry policy
+func (c *RedisCache) retry(ctx context.Context, fn func(context.Context) error) error {
+	return c.retryPolicy.run(ctx, fn)
+}
+
+// run runs fn until it succeeds or the policy gives up, and returns the last error.
+func (p RetryPolicy) run(ctx context.Context, fn func(context.Context) error) error {
+	if p.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Deadline)
+		defer cancel()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// retryable checks the error is retryable or not
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// backoff returns waiting time before the next attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 && delay > 0 {
+		delay -= time.Duration(rand.Float64() * p.Jitter * float64(delay))
+	}
+	return delay
+}
@@ -0,0 +1,153 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/evalphobia/eurekache"
+)
+
+func TestIsRetryable(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{redis.ErrNil, false},
+		{errNilPool, false},
+		{eurekache.WrapError(eurekache.ErrConnection, errNilPool), false},
+		{context.Canceled, false},
+		{eurekache.WrapError(eurekache.ErrDecode, errors.New("decode")), false},
+		{redis.Error("ERR wrong number of arguments"), false},
+		{errors.New("unknown"), false},
+		{redis.Error("LOADING Redis is loading the dataset in memory"), true},
+		{redis.Error("BUSY Redis is busy running a script"), true},
+		{eurekache.WrapError(eurekache.ErrConnection, errors.New("refused")), true},
+		{eurekache.WrapError(eurekache.ErrTimeout, context.DeadlineExceeded), true},
+		{io.EOF, true},
+		{redis.ErrPoolExhausted, true},
+		{&net.OpError{Op: "dial", Err: errors.New("refused")}, true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(tt.expected, IsRetryable(tt.err), "%v", tt.err)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	assert := assert.New(t)
+
+	p := RetryPolicy{
+		BaseDelay: 10 * time.Millisecond,
+		MaxDelay:  50 * time.Millisecond,
+	}
+	assert.Equal(10*time.Millisecond, p.backoff(1))
+	assert.Equal(20*time.Millisecond, p.backoff(2))
+	assert.Equal(40*time.Millisecond, p.backoff(3))
+	assert.Equal(50*time.Millisecond, p.backoff(4))
+	assert.Equal(50*time.Millisecond, p.backoff(100))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.backoff(2)
+		assert.True(d >= 10*time.Millisecond)
+		assert.True(d <= 20*time.Millisecond)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	assert := assert.New(t)
+	key := "keyTestRetry"
+
+	var dialed int32
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			// fail the first two connections
+			if atomic.AddInt32(&dialed, 1) <= 2 {
+				return redis.Dial("tcp", "127.0.0.1:0")
+			}
+			return redis.Dial("tcp", "127.0.0.1:6379")
+		},
+	}
+
+	c := NewRedisCache(pool)
+	c.SetPrefix(testRedisPrefix)
+
+	// no retry by default
+	err := c.Set(key, "value")
+	assert.Error(err)
+	assert.EqualValues(1, atomic.LoadInt32(&dialed))
+
+	c.SetRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+	})
+	assert.NoError(c.Set(key, "value"))
+	assert.EqualValues(3, atomic.LoadInt32(&dialed))
+
+	var v string
+	ok, err := c.Lookup(key, &v)
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("value", v)
+
+	// batch
+	assert.NoError(c.SetMulti([]eurekache.WriteEntry{{Key: key, Value: nil}}))
+	assert.False(c.Get(key, &v))
+}
+
+func TestRetryGiveUp(t *testing.T) {
+	assert := assert.New(t)
+
+	var dialed int32
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			atomic.AddInt32(&dialed, 1)
+			return redis.Dial("tcp", "127.0.0.1:0")
+		},
+	}
+	c := NewRedisCache(pool)
+
+	// max attempts
+	c.SetRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+	})
+	var v string
+	ok, err := c.Lookup("key", &v)
+	assert.False(ok)
+	assert.True(errors.Is(err, eurekache.ErrConnection))
+	assert.EqualValues(3, atomic.LoadInt32(&dialed))
+
+	// deadline
+	atomic.StoreInt32(&dialed, 0)
+	c.SetRetryPolicy(RetryPolicy{
+		MaxAttempts: 100,
+		BaseDelay:   20 * time.Millisecond,
+		Deadline:    50 * time.Millisecond,
+	})
+	start := time.Now()
+	assert.Error(c.Set("key", "value"))
+	assert.True(time.Since(start) < 100*time.Millisecond)
+	assert.True(atomic.LoadInt32(&dialed) < 5)
+
+	// custom classification
+	atomic.StoreInt32(&dialed, 0)
+	c.SetRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		Retryable: func(error) bool {
+			return false
+		},
+	})
+	assert.Error(c.InvalidateTag("tag"))
+	assert.EqualValues(1, atomic.LoadInt32(&dialed))
+}
@@ -0,0 +1,108 @@
+package rediscache
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+// TrackSelect wraps the dial function of redis.Pool to keep the selected db of each connection,
+// and RedisCache sends SELECT only when the connection uses another db.
+// Use it as Dial of the pool passed into NewRedisCache, then the pool can be shared with RedisCache using another db.
+//
+//	pool := &redis.Pool{Dial: rediscache.TrackSelect(dial)}
+//
+// The pools created by NewRedisCacheWithOptions and Sentinel already use it.
+// redigo v1.6.0 has no DialContext of redis.Pool, so Dial is the only function to wrap.
+func TrackSelect(dial func() (redis.Conn, error)) func() (redis.Conn, error) {
+	return func() (redis.Conn, error) {
+		conn, err := dial()
+		if err != nil {
+			return nil, err
+		}
+		// db is unknown until the first SELECT, because dial may select db
+		return &selectConn{Conn: conn}, nil
+	}
+}
+
+// selectConn is redis.Conn keeping the selected db
+type selectConn struct {
+	redis.Conn
+	db    string // empty when unknown
+	multi bool
+}
+
+// Do sends the command, and SELECT for the current db is skipped.
+func (c *selectConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if c.selected(cmd, args) {
+		return "OK", nil
+	}
+
+	reply, err := c.Conn.Do(cmd, args...)
+	c.update(cmd, args, err == nil)
+	return reply, err
+}
+
+// DoWithTimeout sends the command with timeout, and SELECT for the current db is skipped.
+func (c *selectConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	if c.selected(cmd, args) {
+		return "OK", nil
+	}
+
+	reply, err := redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+	c.update(cmd, args, err == nil)
+	return reply, err
+}
+
+// Send writes the command, and the db selected by pipeline is regarded as unknown.
+func (c *selectConn) Send(cmd string, args ...interface{}) error {
+	err := c.Conn.Send(cmd, args...)
+	c.update(cmd, args, false)
+	return err
+}
+
+// ReceiveWithTimeout receives the reply with timeout.
+func (c *selectConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+// selected checks the command is SELECT for the current db
+func (c *selectConn) selected(cmd string, args []interface{}) bool {
+	return c.db != "" && !c.multi && len(args) == 1 &&
+		strings.EqualFold(cmd, "SELECT") && selectArg(args[0]) == c.db
+}
+
+// update updates the state of the connection after sending the command
+func (c *selectConn) update(cmd string, args []interface{}, ok bool) {
+	switch strings.ToUpper(cmd) {
+	case "SELECT":
+		c.db = ""
+		if ok && !c.multi && len(args) == 1 {
+			c.db = selectArg(args[0])
+		}
+	case "MULTI":
+		c.multi = true
+	case "EXEC", "DISCARD", "RESET":
+		// SELECT may be queued in the transaction
+		c.multi = false
+		c.db = ""
+	}
+}
+
+// underlyingConn returns the connection wrapped by selectConn
+func underlyingConn(conn redis.Conn) redis.Conn {
+	if sc, ok := conn.(*selectConn); ok {
+		return sc.Conn
+	}
+	return conn
+}
+
+// selectArg formats the argument of SELECT
+func selectArg(arg interface{}) string {
+	if b, ok := arg.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(arg)
+}
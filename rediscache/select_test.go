@@ -0,0 +1,182 @@
+package rediscache
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackSelect(t *testing.T) {
+	assert := assert.New(t)
+
+	counter := &commandCounter{}
+	pool := &redis.Pool{
+		MaxIdle: 1,
+		Dial:    TrackSelect(counter.dial),
+	}
+	c0 := NewRedisCache(pool)
+	c0.SetPrefix(testRedisPrefix)
+	c1 := NewRedisCache(pool)
+	c1.SetPrefix(testRedisPrefix)
+	c1.Select(1)
+
+	conn, err := redis.Dial("tcp", "127.0.0.1:6379", redis.DialDatabase(1))
+	assert.NoError(err)
+	conn.Do("DEL", testRedisPrefix+"keyTestTrackSelect")
+	conn.Close()
+
+	// SELECT is sent at the first use
+	assert.NoError(c0.Set("keyTestTrackSelect", "value0"))
+	assert.EqualValues(1, counter.selects.Load())
+
+	var v string
+	assert.True(c0.Get("keyTestTrackSelect", &v))
+	assert.Equal("value0", v)
+	assert.EqualValues(1, counter.selects.Load())
+
+	// the pool is shared with another db
+	assert.False(c1.Get("keyTestTrackSelect", &v))
+	assert.EqualValues(2, counter.selects.Load())
+	assert.NoError(c1.Set("keyTestTrackSelect", "value1"))
+	assert.True(c1.Get("keyTestTrackSelect", &v))
+	assert.Equal("value1", v)
+	assert.EqualValues(2, counter.selects.Load())
+
+	assert.True(c0.Get("keyTestTrackSelect", &v))
+	assert.Equal("value0", v)
+	assert.EqualValues(3, counter.selects.Load())
+
+	conn, err = pool.Dial()
+	assert.NoError(err)
+	defer conn.Close()
+	assert.IsType(&selectConn{}, conn)
+	assert.IsType(&countConn{}, underlyingConn(conn))
+
+	// the pool is not modified by NewRedisCache
+	pool = counter.newPool(1)
+	NewRedisCache(pool)
+	conn, err = pool.Dial()
+	assert.NoError(err)
+	defer conn.Close()
+	assert.IsType(&countConn{}, conn)
+}
+
+func TestSelectConn(t *testing.T) {
+	assert := assert.New(t)
+
+	counter := &commandCounter{}
+	conn, err := counter.dial()
+	assert.NoError(err)
+	sc := &selectConn{Conn: conn}
+	defer sc.Close()
+
+	_, err = sc.Do("SELECT", "1")
+	assert.NoError(err)
+	_, err = sc.Do("select", 1)
+	assert.NoError(err)
+	_, err = sc.Do("SELECT", []byte("1"))
+	assert.NoError(err)
+	assert.EqualValues(1, counter.selects.Load())
+	assert.Equal("1", sc.db)
+
+	// failed SELECT
+	_, err = sc.Do("SELECT", "x")
+	assert.Error(err)
+	assert.Equal("", sc.db)
+	sc.Do("SELECT", "1")
+	assert.EqualValues(3, counter.selects.Load())
+
+	// SELECT in the transaction
+	sc.Do("MULTI")
+	reply, _ := sc.Do("SELECT", "1")
+	assert.Equal("QUEUED", reply)
+	sc.Do("EXEC")
+	assert.Equal("", sc.db)
+	assert.EqualValues(4, counter.selects.Load())
+
+	// SELECT by pipeline
+	sc.Do("SELECT", "0")
+	sc.Send("SELECT", "1")
+	sc.Flush()
+	sc.Receive()
+	assert.Equal("", sc.db)
+}
+
+func BenchmarkGet(b *testing.B) {
+	run := func(b *testing.B, c *RedisCache, counter *commandCounter) {
+		c.SetPrefix(testRedisPrefix)
+		c.Select(1)
+		c.Set("keyBenchmarkGet", "value")
+
+		var v string
+		counter.commands.Store(0)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			c.Get("keyBenchmarkGet", &v)
+		}
+		b.ReportMetric(float64(counter.commands.Load())/float64(b.N), "roundtrips/op")
+	}
+
+	b.Run("SelectEveryTime", func(b *testing.B) {
+		counter := &commandCounter{}
+		run(b, NewRedisCache(counter.newPool(1)), counter)
+	})
+	b.Run("SelectOnce", func(b *testing.B) {
+		counter := &commandCounter{}
+		pool := &redis.Pool{
+			MaxIdle: 1,
+			Dial:    TrackSelect(counter.dial),
+		}
+		run(b, NewRedisCache(pool), counter)
+	})
+}
+
+// commandCounter counts commands sent to redis
+type commandCounter struct {
+	commands atomic.Int64
+	selects  atomic.Int64
+}
+
+func (c *commandCounter) newPool(maxIdle int) *redis.Pool {
+	return &redis.Pool{
+		MaxIdle: maxIdle,
+		Dial:    c.dial,
+	}
+}
+
+func (c *commandCounter) dial() (redis.Conn, error) {
+	conn, err := redis.Dial("tcp", "127.0.0.1:6379")
+	if err != nil {
+		return nil, err
+	}
+	return &countConn{Conn: conn, counter: c}, nil
+}
+
+type countConn struct {
+	redis.Conn
+	counter *commandCounter
+}
+
+func (c *countConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	c.count(cmd)
+	return c.Conn.Do(cmd, args...)
+}
+
+func (c *countConn) Send(cmd string, args ...interface{}) error {
+	c.count(cmd)
+	return c.Conn.Send(cmd, args...)
+}
+
+func (c *countConn) count(cmd string) {
+	switch strings.ToUpper(cmd) {
+	case "":
+	case "SELECT":
+		c.counter.selects.Add(1)
+		fallthrough
+	default:
+		c.counter.commands.Add(1)
+	}
+}
@@ -0,0 +1,352 @@
+package rediscache
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+)
+
+const (
+	defaultSentinelCheckInterval = time.Second
+	defaultSentinelMaxIdle       = 3
+	defaultSentinelIdleTimeout   = 240 * time.Second
+	defaultSentinelDialTimeout   = time.Second
+)
+
+var (
+	errNoSentinel = errors.New("no sentinel is available")
+	errNoMaster   = errors.New("master is not found in sentinel")
+)
+
+// Sentinel discovers the master and replicas of Redis from Redis Sentinel.
+type Sentinel struct {
+	addrs         []string
+	masterName    string
+	dial          func(addr string) (redis.Conn, error)
+	checkInterval time.Duration
+
+	mu         sync.Mutex
+	master     string
+	replicas   []string
+	checkedAt  time.Time
+	refreshing chan struct{}
+	refreshErr error
+}
+
+// NewSentinel returns initialized Sentinel with the addresses of sentinels and the master name.
+// Connecting and writing to sentinels and redis servers are timed out after a second by default.
+func NewSentinel(addrs []string, masterName string) *Sentinel {
+	return &Sentinel{
+		addrs:      addrs,
+		masterName: masterName,
+		dial: func(addr string) (redis.Conn, error) {
+			return redis.Dial("tcp", addr,
+				redis.DialConnectTimeout(defaultSentinelDialTimeout),
+				redis.DialWriteTimeout(defaultSentinelDialTimeout),
+			)
+		},
+		checkInterval: defaultSentinelCheckInterval,
+	}
+}
+
+// SetDialFunc sets function to connect to sentinels and redis servers
+func (s *Sentinel) SetDialFunc(fn func(addr string) (redis.Conn, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dial = fn
+}
+
+// SetCheckInterval sets interval to check the current master and replicas.
+// pooled connections to old master are discarded after the failover is detected.
+func (s *Sentinel) SetCheckInterval(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkInterval = d
+}
+
+// MasterAddr asks sentinels the address of the current master.
+func (s *Sentinel) MasterAddr() (string, error) {
+	var addr string
+	err := s.query(func(conn redis.Conn) error {
+		res, err := redis.Strings(sentinelDo(conn, "get-master-addr-by-name", s.masterName))
+		switch {
+		case err == redis.ErrNil:
+			return errNoMaster
+		case err != nil:
+			return err
+		case len(res) != 2:
+			return errNoMaster
+		}
+		addr = net.JoinHostPort(res[0], res[1])
+		return nil
+	})
+	return addr, err
+}
+
+// ReplicaAddrs asks sentinels the addresses of healthy replicas.
+func (s *Sentinel) ReplicaAddrs() ([]string, error) {
+	var addrs []string
+	err := s.query(func(conn redis.Conn) error {
+		res, err := redis.Values(sentinelDo(conn, "replicas", s.masterName))
+		if err != nil {
+			// for Redis older than 5.0
+			res, err = redis.Values(sentinelDo(conn, "slaves", s.masterName))
+		}
+		if err != nil {
+			return err
+		}
+
+		addrs = nil
+		for _, r := range res {
+			info, err := redis.StringMap(r, nil)
+			if err != nil {
+				return err
+			}
+			if isDownReplica(info["flags"]) {
+				continue
+			}
+			addrs = append(addrs, net.JoinHostPort(info["ip"], info["port"]))
+		}
+		return nil
+	})
+	return addrs, err
+}
+
+// MasterPool returns redis.Pool connecting to the current master.
+// The connection to old master is discarded when it's borrowed after the failover.
+func (s *Sentinel) MasterPool() *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:     defaultSentinelMaxIdle,
+		IdleTimeout: defaultSentinelIdleTimeout,
+		Dial: TrackSelect(func() (redis.Conn, error) {
+			master, _, err := s.current()
+			if err != nil {
+				return nil, err
+			}
+			return s.dialNode(master)
+		}),
+		TestOnBorrow: func(conn redis.Conn, _ time.Time) error {
+			master, _, err := s.current()
+			if err != nil {
+				return err
+			}
+			return checkNodeAddr(conn, master)
+		},
+	}
+}
+
+// ReplicaPool returns redis.Pool connecting to one of replicas.
+// The master is used when no replica is available.
+func (s *Sentinel) ReplicaPool() *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:     defaultSentinelMaxIdle,
+		IdleTimeout: defaultSentinelIdleTimeout,
+		Dial: TrackSelect(func() (redis.Conn, error) {
+			master, replicas, err := s.current()
+			if err != nil {
+				return nil, err
+			}
+
+			for _, i := range rand.Perm(len(replicas)) {
+				conn, err := s.dialNode(replicas[i])
+				if err == nil {
+					return conn, nil
+				}
+			}
+			return s.dialNode(master)
+		}),
+		TestOnBorrow: func(conn redis.Conn, _ time.Time) error {
+			master, replicas, err := s.current()
+			if err != nil {
+				return err
+			}
+			return checkNodeAddr(conn, append([]string{master}, replicas...)...)
+		},
+	}
+}
+
+// current returns the cached master and replicas, and refreshes them after the check interval.
+// Only one caller refreshes at a time, and the others use the cached addresses,
+// or wait for the refresh when nothing is cached yet.
+// When the refresh fails, the last known addresses are used until the next check.
+func (s *Sentinel) current() (string, []string, error) {
+	s.mu.Lock()
+	master, replicas := s.master, s.replicas
+	if master != "" && time.Since(s.checkedAt) < s.checkInterval {
+		s.mu.Unlock()
+		return master, replicas, nil
+	}
+
+	done := s.refreshing
+	if done == nil {
+		done = make(chan struct{})
+		s.refreshing = done
+		s.mu.Unlock()
+
+		s.refresh(done)
+	} else {
+		s.mu.Unlock()
+		if master != "" {
+			return master, replicas, nil
+		}
+		<-done
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.master == "" {
+		return "", nil, s.refreshErr
+	}
+	return s.master, s.replicas, nil
+}
+
+// refresh asks sentinels the master and replicas, and wakes up the callers waiting for it
+func (s *Sentinel) refresh(done chan struct{}) error {
+	master, err := s.MasterAddr()
+	var replicas []string
+	if err == nil {
+		replicas, _ = s.ReplicaAddrs()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.master = master
+		s.replicas = replicas
+	}
+	// failed refresh is also retried after the interval, not on every borrow
+	s.checkedAt = time.Now()
+	s.refreshErr = err
+	s.refreshing = nil
+	close(done)
+	return err
+}
+
+// query runs fn with the connection to sentinels in order until it succeeds.
+// the sentinel responded is moved to the head.
+func (s *Sentinel) query(fn func(redis.Conn) error) error {
+	s.mu.Lock()
+	addrs := append([]string{}, s.addrs...)
+	dial := s.dial
+	s.mu.Unlock()
+
+	lastErr := errNoSentinel
+	for i, addr := range addrs {
+		conn, err := dial(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		err = fn(conn)
+		conn.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if i != 0 {
+			s.mu.Lock()
+			s.addrs = append([]string{addr}, append(addrs[:i:i], addrs[i+1:]...)...)
+			s.mu.Unlock()
+		}
+		return nil
+	}
+	return lastErr
+}
+
+// sentinelDo sends SENTINEL command, and the reply is timed out when the connection supports it
+func sentinelDo(conn redis.Conn, args ...interface{}) (interface{}, error) {
+	if _, ok := conn.(redis.ConnWithTimeout); ok {
+		return redis.DoWithTimeout(conn, defaultSentinelDialTimeout, "SENTINEL", args...)
+	}
+	return conn.Do("SENTINEL", args...)
+}
+
+// dialNode connects to the redis server
+func (s *Sentinel) dialNode(addr string) (redis.Conn, error) {
+	s.mu.Lock()
+	dial := s.dial
+	s.mu.Unlock()
+
+	conn, err := dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &nodeConn{
+		Conn: conn,
+		addr: addr,
+	}, nil
+}
+
+// NewSentinelCache returns RedisCache connecting to the master discovered by sentinels.
+func NewSentinelCache(addrs []string, masterName string) *RedisCache {
+	s := NewSentinel(addrs, masterName)
+	c := NewRedisCache(s.MasterPool())
+	c.sentinel = s
+	return c
+}
+
+// Sentinel returns Sentinel used by the cache, or nil when it's not created by NewSentinelCache.
+func (c *RedisCache) Sentinel() *Sentinel {
+	return c.sentinel
+}
+
+// SetReadFromReplicas routes read operations into replicas discovered by sentinels.
+// It does nothing when the cache is not created by NewSentinelCache.
+func (c *RedisCache) SetReadFromReplicas(enabled bool) {
+	switch {
+	case c.sentinel == nil:
+		return
+	case enabled:
+		c.readPool = c.sentinel.ReplicaPool()
+	default:
+		c.readPool = nil
+	}
+}
+
+// nodeConn is redis.Conn having the address of the server
+type nodeConn struct {
+	redis.Conn
+	addr string
+}
+
+// DoWithTimeout sends the command with timeout.
+func (c *nodeConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+// ReceiveWithTimeout receives the reply with timeout.
+func (c *nodeConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+// checkNodeAddr returns error when the connection is not for one of the addresses
+func checkNodeAddr(conn redis.Conn, addrs ...string) error {
+	nc, ok := underlyingConn(conn).(*nodeConn)
+	if !ok {
+		return nil
+	}
+	for _, addr := range addrs {
+		if nc.addr == addr {
+			return nil
+		}
+	}
+	return errors.New("redis server is changed by failover: " + nc.addr)
+}
+
+// isDownReplica checks flags of the replica from sentinel
+func isDownReplica(flags string) bool {
+	for _, f := range strings.Split(flags, ",") {
+		switch f {
+		case "s_down", "o_down", "disconnected":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,303 @@
+package rediscache
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentinelAddrs(t *testing.T) {
+	assert := assert.New(t)
+
+	sentinel := newFakeSentinel("node1:7000", "node2:7001")
+	sentinel.setReplicas(
+		[2]string{"node2:7001", "slave"},
+		[2]string{"node3:7002", "slave,s_down"},
+	)
+	s := NewSentinel([]string{"dead:26379", "sentinel:26379"}, "mymaster")
+	s.SetDialFunc(sentinel.dial)
+
+	// the dead sentinel is skipped
+	addr, err := s.MasterAddr()
+	assert.NoError(err)
+	assert.Equal("node1:7000", addr)
+	assert.Equal([]string{"sentinel:26379", "dead:26379"}, s.addrs)
+
+	// the down replica is skipped
+	addrs, err := s.ReplicaAddrs()
+	assert.NoError(err)
+	assert.Equal([]string{"node2:7001"}, addrs)
+
+	// unknown master
+	s = NewSentinel([]string{"sentinel:26379"}, "unknown")
+	s.SetDialFunc(sentinel.dial)
+	_, err = s.MasterAddr()
+	assert.Equal(errNoMaster, err)
+
+	// no sentinel
+	s = NewSentinel(nil, "mymaster")
+	_, err = s.MasterAddr()
+	assert.Equal(errNoSentinel, err)
+}
+
+func TestSentinelCache(t *testing.T) {
+	assert := assert.New(t)
+
+	sentinel := newFakeSentinel("node1:7000", "node2:7001")
+	c := NewSentinelCache([]string{"sentinel:26379"}, "mymaster")
+	c.Sentinel().SetDialFunc(sentinel.dial)
+	c.Sentinel().SetCheckInterval(0)
+
+	assert.NoError(c.Set("key", "value"))
+	assert.Equal(1, sentinel.node("node1:7000").len())
+
+	var v string
+	assert.True(c.Get("key", &v))
+	assert.Equal("value", v)
+
+	// failover: pooled connections to the old master are discarded
+	sentinel.node("node2:7001").copyFrom(sentinel.node("node1:7000"))
+	sentinel.setMaster("node2:7001")
+	assert.NoError(c.Set("key2", "value2"))
+	assert.Equal(1, sentinel.node("node2:7001").sets("key2"))
+	assert.Equal(0, sentinel.node("node1:7000").sets("key2"))
+	assert.True(c.Get("key", &v))
+	assert.Equal("value", v)
+}
+
+func TestSentinelCacheReadFromReplicas(t *testing.T) {
+	assert := assert.New(t)
+
+	sentinel := newFakeSentinel("node1:7000", "node2:7001")
+	sentinel.setReplicas([2]string{"node2:7001", "slave"})
+	c := NewSentinelCache([]string{"sentinel:26379"}, "mymaster")
+	c.Sentinel().SetDialFunc(sentinel.dial)
+
+	assert.NoError(c.Set("key", "value"))
+	sentinel.node("node2:7001").copyFrom(sentinel.node("node1:7000"))
+
+	c.SetReadFromReplicas(true)
+	master := sentinel.node("node1:7000").count()
+	var v string
+	assert.True(c.Get("key", &v))
+	assert.Equal("value", v)
+	assert.Equal(master, sentinel.node("node1:7000").count())
+	assert.Equal(1, sentinel.node("node2:7001").count())
+
+	// writes are sent to the master
+	assert.NoError(c.Set("key", "value2"))
+	assert.Equal(2, sentinel.node("node1:7000").sets("key"))
+
+	c.SetReadFromReplicas(false)
+	assert.True(c.Get("key", &v))
+	assert.Equal("value2", v)
+
+	// not created by NewSentinelCache
+	c = NewRedisCache(nil)
+	c.SetReadFromReplicas(true)
+	assert.Nil(c.readPool)
+	assert.Nil(c.Sentinel())
+}
+
+func TestSentinelCurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	sentinel := newFakeSentinel("node1:7000")
+	sentinel.setDelay(20 * time.Millisecond)
+	s := NewSentinel([]string{"sentinel:26379"}, "mymaster")
+	s.SetDialFunc(sentinel.dial)
+	s.SetCheckInterval(time.Hour)
+
+	current := func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				master, _, err := s.current()
+				assert.NoError(err)
+				assert.Equal("node1:7000", master)
+			}()
+		}
+		wg.Wait()
+	}
+
+	// concurrent callers wait for one refresh when nothing is cached
+	current()
+	assert.Equal(2, sentinel.dialCount())
+
+	// cached addresses are used within the interval
+	current()
+	assert.Equal(2, sentinel.dialCount())
+
+	// only one caller refreshes after the interval
+	s.SetCheckInterval(0)
+	current()
+	assert.Equal(4, sentinel.dialCount())
+
+	// last known addresses are used while sentinels are down, and refresh is retried after the interval
+	sentinel.setDown(true)
+	s.SetCheckInterval(200 * time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	current()
+	current()
+	assert.Equal(5, sentinel.dialCount())
+
+	time.Sleep(200 * time.Millisecond)
+	current()
+	assert.Equal(6, sentinel.dialCount())
+
+	sentinel.setDown(false)
+	time.Sleep(200 * time.Millisecond)
+	current()
+	assert.Equal(8, sentinel.dialCount())
+
+	// refresh error
+	s = NewSentinel([]string{"dead:26379"}, "mymaster")
+	s.SetDialFunc(sentinel.dial)
+	_, _, err := s.current()
+	assert.Error(err)
+}
+
+func TestSentinelCacheSentinelDown(t *testing.T) {
+	assert := assert.New(t)
+
+	sentinel := newFakeSentinel("node1:7000")
+	c := NewSentinelCache([]string{"sentinel:26379"}, "mymaster")
+	c.Sentinel().SetDialFunc(sentinel.dial)
+	c.Sentinel().SetCheckInterval(time.Hour)
+	assert.NoError(c.Set("key", "value"))
+
+	// the master is still used after the check interval
+	sentinel.setDown(true)
+	c.Sentinel().SetCheckInterval(200 * time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	dialed := sentinel.dialCount()
+
+	var v string
+	for i := 0; i < 10; i++ {
+		assert.True(c.Get("key", &v))
+		assert.Equal("value", v)
+		assert.NoError(c.Set("key", "value"))
+	}
+	assert.Equal(dialed+1, sentinel.dialCount())
+}
+
+func TestIsDownReplica(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.False(isDownReplica("slave"))
+	assert.True(isDownReplica("slave,s_down"))
+	assert.True(isDownReplica("o_down,slave"))
+	assert.True(isDownReplica("slave,disconnected"))
+}
+
+// fakeSentinel is in-memory redis sentinel monitoring the nodes of fakeCluster
+type fakeSentinel struct {
+	cluster *fakeCluster
+
+	mu       sync.Mutex
+	master   string
+	replicas [][2]string
+	delay    time.Duration
+	dialed   int
+	down     bool
+}
+
+func newFakeSentinel(master string, addrs ...string) *fakeSentinel {
+	return &fakeSentinel{
+		cluster: newFakeCluster(append([]string{master}, addrs...)...),
+		master:  master,
+	}
+}
+
+func (s *fakeSentinel) node(addr string) *fakeNode {
+	return s.cluster.node(addr)
+}
+
+func (s *fakeSentinel) setMaster(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.master = addr
+}
+
+func (s *fakeSentinel) setReplicas(replicas ...[2]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replicas = replicas
+}
+
+func (s *fakeSentinel) setDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+func (s *fakeSentinel) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+func (s *fakeSentinel) dialCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dialed
+}
+
+func (s *fakeSentinel) dial(addr string) (redis.Conn, error) {
+	switch {
+	case strings.HasPrefix(addr, "sentinel"):
+		s.mu.Lock()
+		s.dialed++
+		delay, down := s.delay, s.down
+		s.mu.Unlock()
+		time.Sleep(delay)
+		if down {
+			return nil, errors.New("connection refused: " + addr)
+		}
+		return &fakeSentinelConn{sentinel: s}, nil
+	case s.cluster.node(addr) != nil:
+		return &fakeConn{node: s.cluster.node(addr)}, nil
+	}
+	return nil, errors.New("connection refused: " + addr)
+}
+
+type fakeSentinelConn struct {
+	fakeConn
+	sentinel *fakeSentinel
+}
+
+func (c *fakeSentinelConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	s := c.sentinel
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if strings.ToUpper(cmd) != "SENTINEL" || len(args) != 2 || args[1] != "mymaster" {
+		return nil, nil
+	}
+
+	switch args[0] {
+	case "get-master-addr-by-name":
+		host, port, _ := net.SplitHostPort(s.master)
+		return []interface{}{[]byte(host), []byte(port)}, nil
+	case "replicas":
+		var reply []interface{}
+		for _, r := range s.replicas {
+			host, port, _ := net.SplitHostPort(r[0])
+			reply = append(reply, []interface{}{
+				[]byte("ip"), []byte(host),
+				[]byte("port"), []byte(port),
+				[]byte("flags"), []byte(r[1]),
+			})
+		}
+		return reply, nil
+	}
+	return nil, redis.Error("ERR unknown sentinel subcommand")
+}
@@ -0,0 +1,382 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/evalphobia/eurekache"
+	"github.com/evalphobia/eurekache/internal/hashring"
+)
+
+const defaultVirtualNodes = 160
+
+var errNoShard = errors.New("no shard node is available")
+
+// ShardedCache is a cache source spreading keys across multiple RedisCache by consistent hashing
+type ShardedCache struct {
+	name         string
+	virtualNodes int
+
+	mu    sync.RWMutex
+	nodes map[string]*shardNode
+	ring  *hashring.Ring[*shardNode]
+}
+
+// ShardStats is statistics of the shard node
+type ShardStats struct {
+	Name   string
+	Hits   int64
+	Misses int64
+	Sets   int64
+	Errors int64
+}
+
+// shardNode is RedisCache in the hash ring
+type shardNode struct {
+	name  string
+	cache *RedisCache
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	sets   atomic.Int64
+	errors atomic.Int64
+}
+
+// NewShardedCache returns initialized empty ShardedCache
+func NewShardedCache() *ShardedCache {
+	return &ShardedCache{
+		name:         "redis-sharded",
+		virtualNodes: defaultVirtualNodes,
+		nodes:        make(map[string]*shardNode),
+	}
+}
+
+// SetName sets the name of the cache source
+func (c *ShardedCache) SetName(name string) {
+	c.name = name
+}
+
+// Name returns the name of the cache source
+func (c *ShardedCache) Name() string {
+	return c.name
+}
+
+// SetVirtualNodes sets the number of virtual nodes for each node in the hash ring. (default: 160)
+// Most of keys are remapped when it's changed after adding nodes.
+func (c *ShardedCache) SetVirtualNodes(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.virtualNodes = n
+	c.rebuild()
+}
+
+// AddNode adds RedisCache into the hash ring with the unique name.
+// The node is replaced when the name exists, and only the keys of the node are remapped.
+func (c *ShardedCache) AddNode(name string, cache *RedisCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodes[name] = &shardNode{
+		name:  name,
+		cache: cache,
+	}
+	c.rebuild()
+}
+
+// RemoveNode removes the node from the hash ring, and only the keys of the node are remapped.
+func (c *ShardedCache) RemoveNode(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.nodes, name)
+	c.rebuild()
+}
+
+// NodeNames returns the names of the nodes
+func (c *ShardedCache) NodeNames() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.nodes))
+	for name := range c.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NodeName returns the name of the node for the key
+func (c *ShardedCache) NodeName(key string) (string, bool) {
+	node := c.node(key)
+	if node == nil {
+		return "", false
+	}
+	return node.name, true
+}
+
+// Stats returns statistics of the nodes in name order
+func (c *ShardedCache) Stats() []ShardStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := make([]ShardStats, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		stats = append(stats, ShardStats{
+			Name:   n.name,
+			Hits:   n.hits.Load(),
+			Misses: n.misses.Load(),
+			Sets:   n.sets.Load(),
+			Errors: n.errors.Load(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// Ping checks the connection to all of the nodes until the deadline of ctx
+func (c *ShardedCache) Ping(ctx context.Context) error {
+	for _, n := range c.allNodes() {
+		err := n.cache.Ping(ctx)
+		if err != nil {
+			return fmt.Errorf("shard node %s: %w", n.name, err)
+		}
+	}
+	return nil
+}
+
+// Get searches cache by given key from the node and returns flag of cache is existed or not.
+// when cache hit, data is assigned.
+func (c *ShardedCache) Get(key string, data interface{}) bool {
+	ok, _ := c.Lookup(key, data)
+	return ok
+}
+
+// Lookup searches cache by given key from the node and returns flag of cache is existed or not, and error.
+// when cache hit, data is assigned.
+func (c *ShardedCache) Lookup(key string, data interface{}) (bool, error) {
+	_, ok, err := c.LookupItem(context.Background(), key, data)
+	return ok, err
+}
+
+// LookupContext searches cache like Lookup, and redis command is timed out at the deadline of ctx.
+func (c *ShardedCache) LookupContext(ctx context.Context, key string, data interface{}) (bool, error) {
+	_, ok, err := c.LookupItem(ctx, key, data)
+	return ok, err
+}
+
+// LookupItem searches cache by given key from the node and returns Item and error.
+// when cache hit, data is assigned.
+func (c *ShardedCache) LookupItem(ctx context.Context, key string, data interface{}) (*eurekache.Item, bool, error) {
+	node := c.node(key)
+	if node == nil {
+		return nil, false, eurekache.WrapError(eurekache.ErrConnection, errNoShard)
+	}
+
+	item, ok, err := node.cache.LookupItem(ctx, key, data)
+	node.recordGet(ok, err)
+	return item, ok, err
+}
+
+// GetInterface searches cache by given key from the node and returns interface value.
+func (c *ShardedCache) GetInterface(key string) (interface{}, bool) {
+	node := c.node(key)
+	if node == nil {
+		return nil, false
+	}
+
+	v, ok := node.cache.GetInterface(key)
+	node.recordGet(ok, nil)
+	return v, ok
+}
+
+// GetGobBytes searches cache by given key from the node and returns gob-encoded value.
+func (c *ShardedCache) GetGobBytes(key string) ([]byte, bool) {
+	node := c.node(key)
+	if node == nil {
+		return nil, false
+	}
+
+	b, ok := node.cache.GetGobBytes(key)
+	node.recordGet(ok, nil)
+	return b, ok
+}
+
+// GetMulti searches multiple cache by given keys from the nodes, and returns values of the keys cache hit.
+// The keys are grouped by node and sent by MGET for each node.
+func (c *ShardedCache) GetMulti(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	groups, err := c.groupKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(keys))
+	for node, keys := range groups {
+		values, err := node.cache.GetMulti(ctx, keys)
+		if err != nil {
+			node.errors.Add(1)
+			return nil, err
+		}
+		node.hits.Add(int64(len(values)))
+		node.misses.Add(int64(len(keys) - len(values)))
+		for k, v := range values {
+			result[k] = v
+		}
+	}
+	return result, nil
+}
+
+// Set sets data into the node.
+func (c *ShardedCache) Set(key string, data interface{}) error {
+	node := c.node(key)
+	if node == nil {
+		return errNoShard
+	}
+	return node.recordSet(node.cache.Set(key, data))
+}
+
+// SetExpire sets data into the node with TTL.
+func (c *ShardedCache) SetExpire(key string, data interface{}, ttl int64) error {
+	node := c.node(key)
+	if node == nil {
+		return errNoShard
+	}
+	return node.recordSet(node.cache.SetExpire(key, data, ttl))
+}
+
+// SetWithTags sets data into the node with TTL and tags.
+// The tags are stored in the node of the key.
+func (c *ShardedCache) SetWithTags(key string, data interface{}, ttl int64, tags ...string) error {
+	node := c.node(key)
+	if node == nil {
+		return errNoShard
+	}
+	return node.recordSet(node.cache.SetWithTags(key, data, ttl, tags...))
+}
+
+// SetMulti sets multiple data into the nodes. The entries are grouped by node.
+func (c *ShardedCache) SetMulti(entries []eurekache.WriteEntry) error {
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	nodes, err := c.nodesOf(keys)
+	if err != nil {
+		return err
+	}
+
+	groups := make(map[*shardNode][]eurekache.WriteEntry)
+	for i, e := range entries {
+		groups[nodes[i]] = append(groups[nodes[i]], e)
+	}
+	for node, entries := range groups {
+		err := node.cache.SetMulti(entries)
+		if err != nil {
+			node.errors.Add(1)
+			return err
+		}
+		node.sets.Add(int64(len(entries)))
+	}
+	return nil
+}
+
+// InvalidateTag deletes all of cached data related to the tag from all of the nodes.
+func (c *ShardedCache) InvalidateTag(tag string) error {
+	for _, n := range c.allNodes() {
+		err := n.cache.InvalidateTag(tag)
+		if err != nil {
+			n.errors.Add(1)
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear does nothing on ShardedCache.
+func (c *ShardedCache) Clear() error {
+	return nil
+}
+
+// node returns the node for the key
+func (c *ShardedCache) node(key string) *shardNode {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lookup(key)
+}
+
+// nodesOf returns the nodes for the keys
+func (c *ShardedCache) nodesOf(keys []string) ([]*shardNode, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.nodes) == 0 {
+		return nil, errNoShard
+	}
+	nodes := make([]*shardNode, len(keys))
+	for i, key := range keys {
+		nodes[i] = c.lookup(key)
+	}
+	return nodes, nil
+}
+
+// groupKeys groups the keys by node
+func (c *ShardedCache) groupKeys(keys []string) (map[*shardNode][]string, error) {
+	nodes, err := c.nodesOf(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[*shardNode][]string)
+	for i, key := range keys {
+		groups[nodes[i]] = append(groups[nodes[i]], key)
+	}
+	return groups, nil
+}
+
+// allNodes returns all of the nodes
+func (c *ShardedCache) allNodes() []*shardNode {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	nodes := make([]*shardNode, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// lookup returns the node for the key from the hash ring
+func (c *ShardedCache) lookup(key string) *shardNode {
+	node, _ := c.ring.Get(key)
+	return node
+}
+
+// rebuild creates the hash ring from the nodes
+func (c *ShardedCache) rebuild() {
+	c.ring = hashring.New(c.nodes, c.virtualNodes)
+}
+
+// recordGet counts the result of get operation
+func (n *shardNode) recordGet(ok bool, err error) {
+	switch {
+	case err != nil:
+		n.errors.Add(1)
+	case ok:
+		n.hits.Add(1)
+	default:
+		n.misses.Add(1)
+	}
+}
+
+// recordSet counts the result of set operation
+func (n *shardNode) recordSet(err error) error {
+	if err != nil {
+		n.errors.Add(1)
+		return err
+	}
+	n.sets.Add(1)
+	return nil
+}
@@ -0,0 +1,124 @@
+package rediscache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/evalphobia/eurekache"
+	"github.com/evalphobia/eurekache/test/helper"
+)
+
+func TestShardedCacheRing(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewShardedCache()
+	_, ok := c.NodeName("key")
+	assert.False(ok)
+
+	c.AddNode("node1", NewRedisCache(nil))
+	c.AddNode("node2", NewRedisCache(nil))
+	c.AddNode("node3", NewRedisCache(nil))
+	assert.Equal([]string{"node1", "node2", "node3"}, c.NodeNames())
+
+	const total = 10000
+	before := make(map[string]string, total)
+	counts := make(map[string]int)
+	for i := 0; i < total; i++ {
+		key := "key" + strconv.Itoa(i)
+		name, ok := c.NodeName(key)
+		assert.True(ok)
+		before[key] = name
+		counts[name]++
+	}
+	for _, count := range counts {
+		assert.InDelta(total/3, count, total/10)
+	}
+
+	// only the keys moved into the new node are remapped
+	c.AddNode("node4", NewRedisCache(nil))
+	var moved int
+	for key, prev := range before {
+		name, _ := c.NodeName(key)
+		if name != prev {
+			assert.Equal("node4", name)
+			moved++
+		}
+	}
+	assert.InDelta(total/4, moved, total/10)
+
+	// the keys go back after removing the node
+	c.RemoveNode("node4")
+	for key, prev := range before {
+		name, _ := c.NodeName(key)
+		assert.Equal(prev, name)
+	}
+}
+
+func TestShardedCache(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewShardedCache()
+	assert.Equal("redis-sharded", c.Name())
+	assert.Equal(errNoShard, c.Set("key", "value"))
+	_, err := c.Lookup("key", new(string))
+	assert.ErrorIs(err, eurekache.ErrConnection)
+
+	pool := helper.TestGetPool()
+	for i := 0; i < 3; i++ {
+		rc := NewRedisCache(pool)
+		rc.SetPrefix(testRedisPrefix + "shard:")
+		rc.Select(i)
+		c.AddNode("db"+strconv.Itoa(i), rc)
+	}
+	assert.NoError(c.Ping(context.Background()))
+
+	var keys []string
+	for i := 0; i < 30; i++ {
+		key := "keyTestShardedCache" + strconv.Itoa(i)
+		keys = append(keys, key)
+		assert.NoError(c.SetWithTags(key, i, 10000, "tagTestShardedCache"))
+	}
+
+	for i, key := range keys {
+		var v int
+		assert.True(c.Get(key, &v))
+		assert.Equal(i, v)
+	}
+	values, err := c.GetMulti(context.Background(), append(keys, "keyTestShardedCacheNothing"))
+	assert.NoError(err)
+	assert.Len(values, 30)
+
+	// stats
+	var sets, hits, misses int64
+	for _, s := range c.Stats() {
+		assert.True(s.Sets > 0, s.Name)
+		sets += s.Sets
+		hits += s.Hits
+		misses += s.Misses
+		assert.Zero(s.Errors)
+	}
+	assert.EqualValues(30, sets)
+	assert.EqualValues(60, hits)
+	assert.EqualValues(1, misses)
+
+	// tags in all of the nodes
+	assert.NoError(c.InvalidateTag("tagTestShardedCache"))
+	for _, key := range keys {
+		var v int
+		assert.False(c.Get(key, &v))
+	}
+
+	// batch
+	assert.NoError(c.SetMulti([]eurekache.WriteEntry{
+		{Key: keys[0], Value: "value0", UseDefaultTTL: true},
+		{Key: keys[1], Value: "value1", UseDefaultTTL: true},
+	}))
+	v, ok := c.GetInterface(keys[1])
+	assert.True(ok)
+	assert.Equal("value1", v)
+	_, ok = c.GetGobBytes(keys[0])
+	assert.True(ok)
+}
@@ -0,0 +1,344 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/evalphobia/eurekache"
+	"github.com/garyburd/redigo/redis"
+)
+
+const (
+	trackingChannel              = "__redis__:invalidate"
+	defaultTrackingRetryInterval = time.Second
+)
+
+var errTrackingClosed = errors.New("tracking is closed")
+
+// EnableTracking enables server-assisted client side caching (CLIENT TRACKING) of Redis 6 or later.
+// Tracking runs in broadcasting mode for keys with the prefix,
+// and the keys changed by other clients are evicted from given cache sources.
+//
+// While tracking is enabled, all of writes are serialized on a dedicated connection
+// to avoid being notified of the changes made by itself (NOLOOP works only for the same connection),
+// so write concurrency of the RedisCache drops to one. Use InvalidationBus for write-heavy workloads.
+// The dedicated connection is reconnected when it's broken, and given cache sources are cleared
+// because the changes may be missed meanwhile.
+func (c *RedisCache) EnableTracking(caches ...eurekache.Cache) error {
+	c.trackingMu.Lock()
+	defer c.trackingMu.Unlock()
+	if c.tracker.Load() != nil {
+		return nil
+	}
+
+	t := &tracker{
+		cache:         c,
+		caches:        caches,
+		retryInterval: defaultTrackingRetryInterval,
+	}
+	err := t.connect()
+	if err != nil {
+		return err
+	}
+
+	c.tracker.Store(t)
+	go t.run()
+	return nil
+}
+
+// DisableTracking disables client side caching.
+func (c *RedisCache) DisableTracking() error {
+	c.trackingMu.Lock()
+	defer c.trackingMu.Unlock()
+
+	t := c.tracker.Swap(nil)
+	if t == nil {
+		return nil
+	}
+	return t.close()
+}
+
+// writeConnContext returns redis.Conn to write data until the deadline of ctx.
+// When tracking is enabled, the dedicated connection is returned.
+func (c *RedisCache) writeConnContext(ctx context.Context) (redis.Conn, error) {
+	t := c.tracker.Load()
+	if t == nil {
+		return c.connContext(ctx)
+	}
+
+	conn, err := t.conn()
+	switch {
+	case err == errTrackingClosed:
+		// tracking is disabled while getting the connection
+		return c.connContext(ctx)
+	case err != nil:
+		return nil, err
+	}
+	return newContextConn(conn), nil
+}
+
+// tracker receives invalidation messages of client side caching
+type tracker struct {
+	cache         *RedisCache
+	caches        []eurekache.Cache
+	retryInterval time.Duration
+
+	// connection subscribing invalidation messages
+	subMu  sync.Mutex
+	sub    redis.Conn
+	subID  atomic.Int64
+	closed bool
+
+	// connection enabled tracking and used for writing data
+	writerMu sync.Mutex
+	writer   redis.Conn
+}
+
+// connect creates connections and enables tracking
+func (t *tracker) connect() error {
+	pool := t.cache.pool
+	if pool == nil {
+		return errNilPool
+	}
+
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
+	if t.closed {
+		return errTrackingClosed
+	}
+
+	sub := pool.Get()
+	id, err := redis.Int64(sub.Do("CLIENT", "ID"))
+	if err != nil {
+		sub.Close()
+		return err
+	}
+
+	sub.Send("SUBSCRIBE", trackingChannel)
+	sub.Flush()
+	_, err = sub.Receive()
+	if err != nil {
+		sub.Close()
+		return err
+	}
+
+	writer, err := t.dialWriter(id)
+	if err != nil {
+		sub.Close()
+		return err
+	}
+
+	t.sub = sub
+	t.subID.Store(id)
+	t.setWriter(writer)
+	return nil
+}
+
+// dialWriter returns the connection enabled tracking with redirection to the subscriber
+func (t *tracker) dialWriter(subID int64) (redis.Conn, error) {
+	writer, err := t.cache.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{"TRACKING", "ON", "REDIRECT", subID, "BCAST", "NOLOOP"}
+	if t.cache.prefix != "" {
+		args = append(args, "PREFIX", t.cache.prefix)
+	}
+	_, err = writer.Do("CLIENT", args...)
+	if err != nil {
+		writer.Close()
+		return nil, err
+	}
+	return writer, nil
+}
+
+// run receives messages until tracking is disabled
+func (t *tracker) run() {
+	for {
+		t.receive(t.sub)
+		t.closeSub()
+		if t.isClosed() {
+			return
+		}
+
+		for {
+			time.Sleep(t.retryInterval)
+			err := t.connect()
+			if err == nil {
+				break
+			}
+			if t.isClosed() {
+				return
+			}
+		}
+		// messages may be missed during reconnection
+		t.clearCaches()
+	}
+}
+
+// receive handles messages until error occurs or the channel is unsubscribed
+func (t *tracker) receive(sub redis.Conn) {
+	for {
+		reply, err := redis.Values(sub.Receive())
+		if err != nil {
+			return
+		}
+
+		var kind string
+		reply, err = redis.Scan(reply, &kind)
+		if err != nil {
+			continue
+		}
+
+		switch kind {
+		case "message":
+			if len(reply) == 2 {
+				t.handle(reply[1])
+			}
+		case "unsubscribe":
+			return
+		}
+	}
+}
+
+// handle evicts invalidated keys from cache sources.
+// nil is sent when the database is flushed.
+func (t *tracker) handle(payload interface{}) {
+	if payload == nil {
+		t.clearCaches()
+		return
+	}
+
+	keys, err := redis.Strings(payload, nil)
+	if err != nil {
+		return
+	}
+
+	prefix := t.cache.prefix
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		key = strings.TrimPrefix(key, prefix)
+		for _, c := range t.caches {
+			c.Set(key, nil)
+		}
+	}
+}
+
+// clearCaches deletes all of data from cache sources
+func (t *tracker) clearCaches() {
+	for _, c := range t.caches {
+		c.Clear()
+	}
+}
+
+// conn returns the writer connection, and the connection is locked until it's closed.
+// The broken writer connection is reconnected.
+func (t *tracker) conn() (redis.Conn, error) {
+	t.writerMu.Lock()
+	if t.writer == nil {
+		t.writerMu.Unlock()
+		return nil, errTrackingClosed
+	}
+	if t.writer.Err() != nil {
+		err := t.reconnectWriter()
+		if err != nil {
+			t.writerMu.Unlock()
+			return nil, err
+		}
+	}
+
+	return &lockedConn{
+		Conn: t.writer,
+		mu:   &t.writerMu,
+	}, nil
+}
+
+// reconnectWriter replaces the broken writer connection, and it's called with writerMu locked.
+// tracking of the broken connection is lost, so cache sources are cleared.
+func (t *tracker) reconnectWriter() error {
+	writer, err := t.dialWriter(t.subID.Load())
+	if err != nil {
+		return err
+	}
+
+	t.writer.Close()
+	t.writer = writer
+	t.clearCaches()
+	return nil
+}
+
+// setWriter replaces the writer connection
+func (t *tracker) setWriter(writer redis.Conn) {
+	t.writerMu.Lock()
+	defer t.writerMu.Unlock()
+
+	if t.writer != nil {
+		t.writer.Do("CLIENT", "TRACKING", "OFF")
+		t.writer.Close()
+	}
+	t.writer = writer
+}
+
+// close stops receiving messages and releases connections
+func (t *tracker) close() error {
+	t.subMu.Lock()
+	t.closed = true
+	var err error
+	if t.sub != nil {
+		// the subscriber is closed by run() after unsubscribing
+		t.sub.Send("UNSUBSCRIBE")
+		err = t.sub.Flush()
+	}
+	t.subMu.Unlock()
+
+	t.setWriter(nil)
+	return err
+}
+
+// closeSub closes the subscriber connection
+func (t *tracker) closeSub() {
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
+
+	if t.sub != nil {
+		t.sub.Close()
+		t.sub = nil
+	}
+}
+
+// isClosed checks if tracking is disabled or not
+func (t *tracker) isClosed() bool {
+	t.subMu.Lock()
+	defer t.subMu.Unlock()
+	return t.closed
+}
+
+// lockedConn is redis.Conn releasing the lock instead of closing the connection
+type lockedConn struct {
+	redis.Conn
+	mu   *sync.Mutex
+	once sync.Once
+}
+
+// DoWithTimeout sends the command with timeout.
+func (c *lockedConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return redis.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+// ReceiveWithTimeout receives the reply with timeout.
+func (c *lockedConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redis.ReceiveWithTimeout(c.Conn, timeout)
+}
+
+// Close releases the lock of the connection
+func (c *lockedConn) Close() error {
+	c.once.Do(c.mu.Unlock)
+	return nil
+}
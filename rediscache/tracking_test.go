@@ -0,0 +1,344 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/evalphobia/eurekache"
+	"github.com/evalphobia/eurekache/memorycache"
+	"github.com/evalphobia/eurekache/test/helper"
+)
+
+func TestTrackerHandle(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewRedisCache(nil)
+	c.SetPrefix(testRedisPrefix)
+	m := memorycache.NewCacheTTL(10)
+	tr := &tracker{
+		cache:  c,
+		caches: []eurekache.Cache{m},
+	}
+
+	m.Set("key1", "value1")
+	m.Set("key2", "value2")
+	m.Set("key3", "value3")
+
+	var result string
+
+	// invalidate keys
+	tr.handle([]interface{}{
+		[]byte(testRedisPrefix + "key1"),
+		[]byte("other:key2"),
+	})
+	assert.False(m.Get("key1", &result))
+	assert.True(m.Get("key2", &result))
+	assert.True(m.Get("key3", &result))
+
+	// flush
+	tr.handle(nil)
+	assert.False(m.Get("key2", &result))
+	assert.False(m.Get("key3", &result))
+}
+
+func TestEnableTracking(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := helper.TestGetPool()
+	c := NewRedisCache(pool)
+	c.SetPrefix(testRedisPrefix)
+	m := memorycache.NewCacheTTL(10)
+
+	_, err := pool.Get().Do("CLIENT", "TRACKING", "OFF")
+	if err != nil {
+		t.Skipf("CLIENT TRACKING is not supported: %s", err.Error())
+	}
+
+	err = c.EnableTracking(m)
+	assert.NoError(err)
+	assert.NotNil(c.tracker.Load())
+	defer c.DisableTracking()
+
+	e := eurekache.New()
+	e.SetCacheSources([]eurekache.Cache{m, c})
+
+	var result string
+
+	// changes made by itself
+	e.Set("keyTracking", "value")
+	time.Sleep(100 * time.Millisecond)
+	assert.True(m.Get("keyTracking", &result))
+
+	// changes made by other client
+	_, err = pool.Get().Do("DEL", testRedisPrefix+"keyTracking")
+	assert.NoError(err)
+	time.Sleep(100 * time.Millisecond)
+	assert.False(m.Get("keyTracking", &result))
+
+	err = c.DisableTracking()
+	assert.NoError(err)
+	assert.Nil(c.tracker.Load())
+}
+
+func TestTrackingFakeServer(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := newFakeTrackingServer()
+	pool := &redis.Pool{
+		MaxIdle: 5,
+		Dial:    srv.dial,
+	}
+	c := NewRedisCache(pool)
+	c.SetPrefix(testRedisPrefix)
+	c.SetRetryPolicy(RetryPolicy{Deadline: time.Second})
+	m := memorycache.NewCacheTTL(10)
+	assert.NoError(c.EnableTracking(m))
+	defer c.DisableTracking()
+
+	e := eurekache.New()
+	e.SetCacheSources([]eurekache.Cache{m, c})
+
+	// writes with the deadline are sent by the dedicated connection, and they are not notified to itself
+	var result string
+	assert.NoError(e.Set("key1", "value1"))
+	assert.NoError(e.Set("key2", "value2"))
+	time.Sleep(20 * time.Millisecond)
+	assert.True(m.Get("key1", &result))
+	assert.True(c.Get("key1", &result))
+	assert.Equal("value1", result)
+
+	// changes made by other client
+	other := pool.Get()
+	_, err := other.Do("DEL", testRedisPrefix+"key1")
+	other.Close()
+	assert.NoError(err)
+	assert.Eventually(func() bool {
+		return !m.Get("key1", &result)
+	}, time.Second, time.Millisecond)
+
+	// the broken writer connection is reconnected, and cache sources are cleared
+	srv.killTracking()
+	assert.NoError(e.Set("key3", "value3"))
+	assert.False(m.Get("key2", &result))
+	assert.NoError(e.Set("key3", "value3"))
+	time.Sleep(20 * time.Millisecond)
+	assert.True(m.Get("key3", &result))
+
+	other = pool.Get()
+	_, err = other.Do("DEL", testRedisPrefix+"key3")
+	other.Close()
+	assert.NoError(err)
+	assert.Eventually(func() bool {
+		return !m.Get("key3", &result)
+	}, time.Second, time.Millisecond)
+}
+
+func TestWriteConnContext(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewRedisCache(helper.TestGetPool())
+	c.SetPrefix(testRedisPrefix)
+
+	// tracking is disabled while writing
+	c.tracker.Store(&tracker{cache: c})
+	assert.NoError(c.Set("keyWriteConnContext", "value"))
+	assert.NoError(c.Ping(context.Background()))
+
+	var result string
+	assert.True(c.Get("keyWriteConnContext", &result))
+	assert.Equal("value", result)
+}
+
+// fakeTrackingServer is in-memory redis server supporting CLIENT TRACKING in broadcasting mode
+type fakeTrackingServer struct {
+	mu      sync.Mutex
+	lastID  int64
+	data    map[string][]byte
+	clients map[int64]*fakeTrackingConn
+}
+
+func newFakeTrackingServer() *fakeTrackingServer {
+	return &fakeTrackingServer{
+		data:    make(map[string][]byte),
+		clients: make(map[int64]*fakeTrackingConn),
+	}
+}
+
+func (s *fakeTrackingServer) dial() (redis.Conn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	c := &fakeTrackingConn{
+		srv:     s,
+		id:      s.lastID,
+		replies: make(chan fakeReply, 100),
+		closed:  make(chan struct{}),
+	}
+	s.clients[c.id] = c
+	return c, nil
+}
+
+// killTracking breaks the connections enabled tracking
+func (s *fakeTrackingServer) killTracking() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, c := range s.clients {
+		if c.redirect != 0 {
+			c.err = io.EOF
+			delete(s.clients, id)
+		}
+	}
+}
+
+// invalidate sends the key to the clients tracking it, except the client changed it
+func (s *fakeTrackingServer) invalidate(key string, from int64) {
+	for _, c := range s.clients {
+		if c.redirect == 0 || c.id == from || !strings.HasPrefix(key, c.prefix) {
+			continue
+		}
+		if target, ok := s.clients[c.redirect]; ok && target.subscribed {
+			target.replies <- fakeReply{reply: []interface{}{
+				[]byte("message"), []byte(trackingChannel), []interface{}{[]byte(key)},
+			}}
+		}
+	}
+}
+
+func (s *fakeTrackingServer) handle(c *fakeTrackingConn, cmd string, args []interface{}) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		if b, ok := arg.([]byte); ok {
+			strs[i] = string(b)
+		} else {
+			strs[i] = fmt.Sprint(arg)
+		}
+	}
+
+	switch strings.ToUpper(cmd) {
+	case "SELECT", "PING":
+		return "OK", nil
+	case "CLIENT":
+		switch strings.ToUpper(strs[0]) {
+		case "ID":
+			return c.id, nil
+		case "TRACKING":
+			c.redirect = 0
+			if strings.ToUpper(strs[1]) == "OFF" {
+				return "OK", nil
+			}
+			for i := 2; i+1 < len(strs); i++ {
+				switch strs[i] {
+				case "REDIRECT":
+					fmt.Sscan(strs[i+1], &c.redirect)
+				case "PREFIX":
+					c.prefix = strs[i+1]
+				}
+			}
+			return "OK", nil
+		}
+	case "SUBSCRIBE":
+		c.subscribed = true
+		return []interface{}{[]byte("subscribe"), []byte(strs[0]), int64(1)}, nil
+	case "UNSUBSCRIBE":
+		c.subscribed = false
+		return []interface{}{[]byte("unsubscribe"), []byte(trackingChannel), int64(0)}, nil
+	case "PUNSUBSCRIBE":
+		return []interface{}{[]byte("punsubscribe"), nil, int64(0)}, nil
+	case "ECHO":
+		return []byte(strs[0]), nil
+	case "GET":
+		v, ok := s.data[strs[0]]
+		if !ok {
+			return nil, nil
+		}
+		return v, nil
+	case "SET":
+		s.data[strs[0]] = []byte(strs[1])
+		s.invalidate(strs[0], c.id)
+		return "OK", nil
+	case "SETEX":
+		s.data[strs[0]] = []byte(strs[2])
+		s.invalidate(strs[0], c.id)
+		return "OK", nil
+	case "DEL":
+		for _, key := range strs {
+			delete(s.data, key)
+			s.invalidate(key, c.id)
+		}
+		return int64(1), nil
+	}
+	return nil, redis.Error("ERR unknown command " + cmd)
+}
+
+type fakeTrackingConn struct {
+	srv *fakeTrackingServer
+
+	// fields below are guarded by srv.mu
+	id         int64
+	redirect   int64
+	prefix     string
+	subscribed bool
+	err        error
+
+	replies   chan fakeReply
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *fakeTrackingConn) Err() error {
+	c.srv.mu.Lock()
+	defer c.srv.mu.Unlock()
+	return c.err
+}
+
+func (c *fakeTrackingConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *fakeTrackingConn) Flush() error { return nil }
+
+func (c *fakeTrackingConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if cmd == "" {
+		return nil, c.Err()
+	}
+	return c.srv.handle(c, cmd, args)
+}
+
+func (c *fakeTrackingConn) DoWithTimeout(_ time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	return c.Do(cmd, args...)
+}
+
+func (c *fakeTrackingConn) Send(cmd string, args ...interface{}) error {
+	reply, err := c.srv.handle(c, cmd, args)
+	c.replies <- fakeReply{reply, err}
+	return nil
+}
+
+func (c *fakeTrackingConn) Receive() (interface{}, error) {
+	select {
+	case r := <-c.replies:
+		return r.reply, r.err
+	case <-c.closed:
+		return nil, errors.New("connection is closed")
+	}
+}
+
+func (c *fakeTrackingConn) ReceiveWithTimeout(_ time.Duration) (interface{}, error) {
+	return c.Receive()
+}
@@ -0,0 +1,64 @@
+package eurekache
+
+import "context"
+
+// StaleLookuper is interface for cache source returning expired data kept for the grace period
+type StaleLookuper interface {
+	LookupStale(context.Context, string, interface{}) (bool, error)
+}
+
+// Loader returns the original data of the key on cache miss
+type Loader func(ctx context.Context, key string) (interface{}, error)
+
+// LookupStale searches cache by given key like LookupContext.
+// When cache sources failed, expired data is searched from the cache sources implementing StaleLookuper,
+// and stale is true when it's returned.
+func (e *Eurekache) LookupStale(ctx context.Context, key string, data interface{}) (ok, stale bool, err error) {
+	ok, err = e.LookupContext(ctx, key, data)
+	if ok || err == nil {
+		return ok, false, err
+	}
+
+	if e.lookupStale(ctx, key, data) {
+		return true, true, nil
+	}
+	return false, false, err
+}
+
+// Load searches cache by given key like LookupContext, and calls loader on cache miss.
+// Loaded data is saved into cache sources with TTL, and the errors of saving are ignored.
+// When the loader failed, expired data is searched from the cache sources implementing StaleLookuper,
+// and stale is true when it's returned.
+func (e *Eurekache) Load(ctx context.Context, key string, data interface{}, ttl int64, loader Loader) (stale bool, err error) {
+	ok, _ := e.LookupContext(ctx, key, data)
+	if ok {
+		return false, nil
+	}
+
+	v, err := loader(ctx, key)
+	if err == nil {
+		e.SetExpire(key, v, ttl)
+		return false, CopyValueWithError(data, v)
+	}
+
+	if e.lookupStale(ctx, key, data) {
+		return true, nil
+	}
+	return false, err
+}
+
+// lookupStale searches expired data from the cache sources in index order
+func (e *Eurekache) lookupStale(ctx context.Context, key string, data interface{}) bool {
+	for _, c := range e.caches {
+		l, ok := c.(StaleLookuper)
+		if !ok {
+			continue
+		}
+
+		ok, _ = l.LookupStale(ctx, key, data)
+		if ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package eurekache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupStale(t *testing.T) {
+	assert := assert.New(t)
+
+	local := &dummyStaleCache{stale: "stale value"}
+	remote := &dummyFailCache{}
+
+	e := New()
+	e.SetCacheSources([]Cache{local, remote})
+
+	// plain cache miss does not return stale data
+	var v string
+	ok, stale, err := e.LookupStale(context.Background(), "key", &v)
+	assert.False(ok)
+	assert.False(stale)
+	assert.NoError(err)
+
+	// cache source failed
+	remote.setError(ErrConnection)
+	ok, stale, err = e.LookupStale(context.Background(), "key", &v)
+	assert.True(ok)
+	assert.True(stale)
+	assert.NoError(err)
+	assert.Equal("stale value", v)
+
+	// fresh data
+	local.value = "value"
+	v = ""
+	ok, stale, err = e.LookupStale(context.Background(), "key", &v)
+	assert.True(ok)
+	assert.False(stale)
+	assert.NoError(err)
+	assert.Equal("value", v)
+
+	// no stale data
+	e.SetCacheSources([]Cache{&dummyStaleCache{}, remote})
+	ok, stale, err = e.LookupStale(context.Background(), "key", &v)
+	assert.False(ok)
+	assert.False(stale)
+	assert.Equal(ErrConnection, err)
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	errLoader := errors.New("loader error")
+	local := &dummyStaleCache{}
+
+	e := New()
+	e.SetCacheSources([]Cache{local})
+
+	var loaded int
+	loader := func(ctx context.Context, key string) (interface{}, error) {
+		loaded++
+		return "loaded " + key, nil
+	}
+
+	// load on cache miss
+	var v string
+	stale, err := e.Load(context.Background(), "key", &v, 1000, loader)
+	assert.False(stale)
+	assert.NoError(err)
+	assert.Equal("loaded key", v)
+	assert.Equal(1, loaded)
+	assert.Equal("loaded key", local.saved)
+	assert.EqualValues(1000, local.ttl)
+
+	// cache hit
+	local.value = "value"
+	stale, err = e.Load(context.Background(), "key", &v, 1000, loader)
+	assert.False(stale)
+	assert.NoError(err)
+	assert.Equal("value", v)
+	assert.Equal(1, loaded)
+
+	// loader failed
+	local.value = ""
+	local.stale = "stale value"
+	failLoader := func(ctx context.Context, key string) (interface{}, error) {
+		return nil, errLoader
+	}
+	stale, err = e.Load(context.Background(), "key", &v, 1000, failLoader)
+	assert.True(stale)
+	assert.NoError(err)
+	assert.Equal("stale value", v)
+
+	// no stale data
+	local.stale = ""
+	v = ""
+	stale, err = e.Load(context.Background(), "key", &v, 1000, failLoader)
+	assert.False(stale)
+	assert.Equal(errLoader, err)
+	assert.Equal("", v)
+
+	// loaded data cannot be copied
+	var i int
+	_, err = e.Load(context.Background(), "key", &i, 1000, loader)
+	assert.True(errors.Is(err, ErrTypeMismatch))
+}
+
+type dummyStaleCache struct {
+	dummyCache
+	value string
+	stale string
+	saved interface{}
+	ttl   int64
+}
+
+func (d *dummyStaleCache) Get(k string, v interface{}) bool {
+	return d.value != "" && CopyValue(v, d.value)
+}
+
+func (d *dummyStaleCache) LookupStale(ctx context.Context, k string, v interface{}) (bool, error) {
+	if d.stale == "" {
+		return false, nil
+	}
+	return true, CopyValueWithError(v, d.stale)
+}
+
+func (d *dummyStaleCache) SetExpire(k string, v interface{}, ttl int64) error {
+	d.saved = v
+	d.ttl = ttl
+	return nil
+}
@@ -0,0 +1,34 @@
+package eurekache
+
+// TagCache is interface for cache source supporting tag-based invalidation
+type TagCache interface {
+	SetWithTags(string, interface{}, int64, ...string) error
+	InvalidateTag(string) error
+}
+
+// SetWithTags sets data with TTL and tags into all of cache sources.
+// Cache sources not implementing TagCache store the data without tags.
+func (e *Eurekache) SetWithTags(key string, data interface{}, ttl int64, tags ...string) error {
+	ttl = e.jitter.Apply(ttl)
+	return e.write(key, func(c Cache) error {
+		if tc, ok := c.(TagCache); ok {
+			return tc.SetWithTags(key, data, ttl, tags...)
+		}
+		return c.SetExpire(key, data, ttl)
+	})
+}
+
+// InvalidateTag deletes all of cached data related to the tag from cache sources.
+func (e *Eurekache) InvalidateTag(tag string) error {
+	for _, c := range e.caches {
+		tc, ok := c.(TagCache)
+		if !ok {
+			continue
+		}
+		if err := tc.InvalidateTag(tag); err != nil {
+			return err
+		}
+	}
+	e.notifyInvalidateTag(tag)
+	return nil
+}
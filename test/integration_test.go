@@ -1,9 +1,12 @@
 package test
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/garyburd/redigo/redis"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/evalphobia/eurekache"
@@ -57,6 +60,68 @@ func TestIntegrationGet(t *testing.T) {
 	assert.Equal("", result)
 }
 
+func TestIntegrationGetWithInfo(t *testing.T) {
+	assert := assert.New(t)
+	key := "testintegrationgetwithinfo"
+	val := "TestIntegrationGetWithInfo"
+
+	mc := memorycache.NewCacheTTL(3)
+	rc := rediscache.NewRedisCache(helper.TestGetPool())
+	rc.SetPrefix(testRedisPrefix)
+
+	e := eurekache.New()
+	e.SetCacheSources([]eurekache.Cache{mc, rc})
+	e.SetExpire(key, val, 1000)
+
+	var result string
+
+	// hit memory
+	ok, info, err := e.GetWithInfo(key, &result)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(val, result)
+	assert.Equal(0, info.Index)
+	assert.Equal("memory", info.Name)
+	assert.Len(info.Sources, 1)
+
+	// hit redis
+	mc.Clear()
+	result = ""
+	ok, info, err = e.GetWithInfo(key, &result)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(val, result)
+	assert.Equal(1, info.Index)
+	assert.Equal("redis", info.Name)
+	assert.Len(info.Sources, 2)
+	assert.False(info.Sources[0].Hit)
+	assert.True(info.CreatedAt < info.ExpiredAt)
+}
+
+type integrationUser struct {
+	ID   int
+	Name string
+}
+
+func TestIntegrationTyped(t *testing.T) {
+	assert := assert.New(t)
+	key := "testintegrationtyped"
+
+	rc := rediscache.NewRedisCache(helper.TestGetPool())
+	rc.SetPrefix(testRedisPrefix)
+
+	e := eurekache.New()
+	e.SetCacheSources([]eurekache.Cache{rc})
+	users := eurekache.NewTyped[*integrationUser](e)
+	users.SetExpire(key, &integrationUser{ID: 1, Name: "alice"}, 1000)
+
+	// hit redis
+	u, ok, err := users.Get(key)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(&integrationUser{ID: 1, Name: "alice"}, u)
+}
+
 func TestIntegrationGetTimeout(t *testing.T) {
 	assert := assert.New(t)
 	key := "testintegrationgettimeout"
@@ -159,3 +224,39 @@ func (d *dummySleepCache) Clear() error {
 func newDummySleepCache(sleep time.Duration) *dummySleepCache {
 	return &dummySleepCache{sleep}
 }
+
+func TestIntegrationLookupStale(t *testing.T) {
+	assert := assert.New(t)
+	key := "testintegrationlookupstale"
+	val := "TestIntegrationLookupStale"
+
+	mc := memorycache.NewCacheTTL(3)
+	mc.SetStaleGrace(1000)
+	rc := rediscache.NewRedisCache(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", "127.0.0.1:0")
+		},
+	})
+
+	e := eurekache.New()
+	e.SetCacheSources([]eurekache.Cache{mc, rc})
+	e.SetExpire(key, val, 10)
+	time.Sleep(20 * time.Millisecond)
+
+	// redis is down and expired data in memory is returned
+	var result string
+	ok, stale, err := e.LookupStale(context.Background(), key, &result)
+	assert.True(ok)
+	assert.True(stale)
+	assert.NoError(err)
+	assert.Equal(val, result)
+
+	// loader is down
+	result = ""
+	stale, err = e.Load(context.Background(), key, &result, 1000, func(ctx context.Context, key string) (interface{}, error) {
+		return nil, errors.New("loader error")
+	})
+	assert.True(stale)
+	assert.NoError(err)
+	assert.Equal(val, result)
+}
@@ -0,0 +1,57 @@
+package eurekache
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// withTimeout runs fn on a goroutine and waits for it until the timeout or ctx is done.
+// ctx given to fn is cancelled when it's timed out, and fn must stop the operation.
+// fn may still be running after withTimeout returns,
+// so fn must not touch the caller's memory except for the variables read only after success.
+func withTimeout(ctx context.Context, d time.Duration, fn func(context.Context)) error {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+		return contextError(ctx)
+	}
+}
+
+// contextError returns ErrTimeout when ctx is timed out, otherwise returns ctx.Err()
+func contextError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return ctx.Err()
+}
+
+// newDestination returns new pointer value of the same type as data,
+// to avoid writing into data from the goroutine after timeout.
+func newDestination(data interface{}) (interface{}, bool) {
+	rv := reflect.ValueOf(data)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return nil, false
+	}
+	return reflect.New(rv.Type().Elem()).Interface(), true
+}
+
+// assignDestination assigns the value of dst created by newDestination into data
+func assignDestination(data, dst interface{}) {
+	reflect.ValueOf(data).Elem().Set(reflect.ValueOf(dst).Elem())
+}
@@ -0,0 +1,147 @@
+package eurekache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	// done
+	err := withTimeout(context.Background(), time.Second, func(ctx context.Context) {})
+	assert.NoError(err)
+
+	// timeout
+	var cancelled int32
+	err = withTimeout(context.Background(), 10*time.Millisecond, func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		atomic.StoreInt32(&cancelled, 1)
+	})
+	assert.Equal(ErrTimeout, err)
+	time.Sleep(20 * time.Millisecond)
+	assert.EqualValues(1, atomic.LoadInt32(&cancelled))
+
+	// cancel
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = withTimeout(ctx, time.Second, func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+	})
+	assert.Equal(context.Canceled, err)
+}
+
+func TestTimeoutDoesNotTouchCaller(t *testing.T) {
+	assert := assert.New(t)
+
+	slow := &dummySlowCache{sleep: 50 * time.Millisecond, value: "slow"}
+	next := &dummySlowCache{value: "next"}
+
+	e := New()
+	e.SetReadTimeout(10 * time.Millisecond)
+	e.SetWriteTimeout(10 * time.Millisecond)
+	e.SetCacheSources([]Cache{slow, next})
+
+	// Get
+	result := "original"
+	ok := e.Get("key", &result)
+	assert.False(ok)
+	assert.Equal("original", result)
+
+	// GetWithInfo
+	ok, _, err := e.GetWithInfo("key", &result)
+	assert.False(ok)
+	assert.Equal(ErrTimeout, err)
+	assert.Equal("original", result)
+
+	// GetInterface and GetGobBytes
+	v, ok := e.GetInterface("key")
+	assert.False(ok)
+	assert.Nil(v)
+	b, ok := e.GetGobBytes("key")
+	assert.False(ok)
+	assert.Nil(b)
+
+	// Set
+	e.Set("key", "value")
+
+	// wait for the slow source and check the next source is not called after timeout
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal("original", result)
+	assert.EqualValues(0, atomic.LoadInt32(&next.called))
+}
+
+func TestLookupContext(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	e.SetCacheSources([]Cache{&dummySlowCache{sleep: 50 * time.Millisecond, value: "value"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	var result string
+	ok, err := e.LookupContext(ctx, "key", &result)
+	assert.False(ok)
+	assert.Equal(ErrTimeout, err)
+	assert.Empty(result)
+
+	// invalid destination
+	ok, err = e.LookupContext(context.Background(), "key", result)
+	assert.False(ok)
+	assert.Error(err)
+}
+
+func TestTimeoutRace(t *testing.T) {
+	e := New()
+	e.SetTimeout(time.Millisecond)
+	e.SetCacheSources([]Cache{&dummySlowCache{sleep: time.Millisecond, value: "value"}})
+
+	for i := 0; i < 100; i++ {
+		var result string
+		e.Get("key", &result)
+		e.GetInterface("key")
+		e.GetGobBytes("key")
+		e.GetWithInfo("key", &result)
+		e.Set("key", "value")
+		_ = result
+	}
+}
+
+// dummySlowCache sleeps before returning the value
+type dummySlowCache struct {
+	dummyCache
+	sleep  time.Duration
+	value  string
+	called int32
+}
+
+func (d *dummySlowCache) Get(k string, v interface{}) bool {
+	atomic.AddInt32(&d.called, 1)
+	time.Sleep(d.sleep)
+	return CopyValue(v, d.value)
+}
+
+func (d *dummySlowCache) GetInterface(k string) (interface{}, bool) {
+	atomic.AddInt32(&d.called, 1)
+	time.Sleep(d.sleep)
+	return d.value, true
+}
+
+func (d *dummySlowCache) GetGobBytes(k string) ([]byte, bool) {
+	atomic.AddInt32(&d.called, 1)
+	time.Sleep(d.sleep)
+	return []byte(d.value), true
+}
+
+func (d *dummySlowCache) Set(k string, v interface{}) error {
+	atomic.AddInt32(&d.called, 1)
+	time.Sleep(d.sleep)
+	return nil
+}
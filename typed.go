@@ -0,0 +1,72 @@
+package eurekache
+
+import (
+	"context"
+	"encoding/gob"
+	"reflect"
+)
+
+// Typed is a type-safe wrapper of Eurekache for the values of T.
+type Typed[T any] struct {
+	cache *Eurekache
+}
+
+// NewTyped returns Typed for given Eurekache and registers the base type of T (e.g. User for *User) into encoding/gob.
+// The registration is skipped when the base type is already registered.
+func NewTyped[T any](e *Eurekache) *Typed[T] {
+	var zero T
+	if t := reflect.TypeOf(zero); t != nil {
+		registerGob(t)
+	}
+
+	return &Typed[T]{
+		cache: e,
+	}
+}
+
+// Get searches cache by given key and returns the value and flag of cache is existed or not.
+// When cache miss, the error of cache sources is returned like Lookup and nil means genuine miss.
+// When cached value is not T, ErrTypeMismatch (or ErrDecode from encoded cache sources) is returned.
+func (t *Typed[T]) Get(key string) (T, bool, error) {
+	return t.GetContext(context.Background(), key)
+}
+
+// GetContext searches cache like Get, and stops searching when ctx is done.
+func (t *Typed[T]) GetContext(ctx context.Context, key string) (T, bool, error) {
+	var result T
+	ok, err := t.cache.LookupContext(ctx, key, &result)
+	if !ok {
+		var zero T
+		return zero, false, err
+	}
+	return result, true, nil
+}
+
+// Set sets data into all of cache sources.
+func (t *Typed[T]) Set(key string, data T) error {
+	return t.cache.Set(key, data)
+}
+
+// SetExpire sets data with TTL.
+func (t *Typed[T]) SetExpire(key string, data T, ttl int64) error {
+	return t.cache.SetExpire(key, data, ttl)
+}
+
+// SetWithTags sets data with TTL and tags.
+func (t *Typed[T]) SetWithTags(key string, data T, ttl int64, tags ...string) error {
+	return t.cache.SetWithTags(key, data, ttl, tags...)
+}
+
+// registerGob registers the base type of t into encoding/gob.
+// gob resolves the values in interface by the base type, so the pointer type is not registered.
+// gob.Register panics when the base type is registered with another name (e.g. by gob.Register(&User{})), and it's ignored.
+func registerGob(t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	defer func() {
+		recover()
+	}()
+	gob.Register(reflect.New(t).Elem().Interface())
+}
@@ -0,0 +1,152 @@
+package eurekache_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/evalphobia/eurekache"
+	"github.com/evalphobia/eurekache/memorycache"
+)
+
+type typedTestUser struct {
+	ID   int
+	Name string
+}
+
+func TestTyped(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	m := memorycache.NewCacheTTL(10)
+	e.SetCacheSources([]Cache{m})
+
+	users := NewTyped[*typedTestUser](e)
+	names := NewTyped[string](e)
+
+	// miss cache
+	u, ok, err := users.Get("user")
+	assert.NoError(err)
+	assert.False(ok)
+	assert.Nil(u)
+
+	// hit cache
+	users.Set("user", &typedTestUser{ID: 1, Name: "alice"})
+	u, ok, err = users.Get("user")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(&typedTestUser{ID: 1, Name: "alice"}, u)
+
+	names.SetExpire("name", "bob", 10000)
+	name, ok, err := names.Get("name")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal("bob", name)
+
+	names.SetWithTags("tagged", "carol", 0, "tag")
+	e.InvalidateTag("tag")
+	_, ok, err = names.Get("tagged")
+	assert.NoError(err)
+	assert.False(ok)
+
+	// type mismatch
+	name, ok, err = names.Get("user")
+	assert.True(errors.Is(err, ErrTypeMismatch))
+	assert.False(ok)
+	assert.Empty(name)
+}
+
+func TestTypedInterface(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	m := memorycache.NewCacheTTL(10)
+	e.SetCacheSources([]Cache{m})
+
+	values := NewTyped[interface{}](e)
+	values.Set("key", 100)
+
+	v, ok, err := values.Get("key")
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(100, v)
+}
+
+type typedTestRegistered struct {
+	ID int
+}
+
+type typedTestBoth struct {
+	ID int
+}
+
+type typedTestBothReverse struct {
+	ID int
+}
+
+func TestTypedRegister(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	e.SetCacheSources([]Cache{memorycache.NewCacheTTL(10)})
+
+	// the type is already registered by the user
+	gob.Register(typedTestRegistered{})
+	assert.NotPanics(func() { NewTyped[*typedTestRegistered](e) })
+	gob.Register(&typedTestBothReverse{})
+	assert.NotPanics(func() { NewTyped[typedTestBothReverse](e) })
+
+	// both of the value and the pointer
+	assert.NotPanics(func() {
+		NewTyped[typedTestBoth](e)
+		NewTyped[*typedTestBoth](e)
+	})
+	assert.NotPanics(func() {
+		NewTyped[*typedTestBothReverse](e)
+		NewTyped[typedTestBothReverse](e)
+	})
+
+	// the pointer in interface is encoded by the registered base type, and decoded as the base type
+	var buf bytes.Buffer
+	var v interface{} = &typedTestBoth{ID: 1}
+	assert.NoError(gob.NewEncoder(&buf).Encode(&v))
+	var result interface{}
+	assert.NoError(gob.NewDecoder(&buf).Decode(&result))
+	assert.Equal(typedTestBoth{ID: 1}, result)
+}
+
+func TestTypedError(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	m := memorycache.NewCacheTTL(10)
+	e.SetCacheSources([]Cache{
+		&typedTestFailCache{CacheTTL: m},
+	})
+
+	names := NewTyped[string](e)
+	names.Set("name", "alice")
+	name, ok, err := names.Get("name")
+	assert.False(ok)
+	assert.True(errors.Is(err, ErrConnection))
+	assert.Empty(name)
+
+	// hit on the next source
+	e.AddCacheSource(m)
+	name, ok, err = names.GetContext(context.Background(), "name")
+	assert.True(ok)
+	assert.NoError(err)
+	assert.Equal("alice", name)
+}
+
+type typedTestFailCache struct {
+	*memorycache.CacheTTL
+}
+
+func (c *typedTestFailCache) Lookup(key string, data interface{}) (bool, error) {
+	return false, ErrConnection
+}
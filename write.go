@@ -0,0 +1,203 @@
+package eurekache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriteStrategy is a strategy for writing data into cache sources
+type WriteStrategy int
+
+// write strategies
+const (
+	// WriteSequential writes data into cache sources one by one in index order
+	WriteSequential WriteStrategy = iota
+	// WriteParallel writes data into all of cache sources at the same time
+	WriteParallel
+	// WriteFirstThenAsync writes data into the first cache source (e.g. memory),
+	// and writes into the rest of cache sources in parallel on background.
+	// Background writes of the same key are run in order.
+	WriteFirstThenAsync
+)
+
+// WriteResult is a result of writing data into a cache source
+type WriteResult struct {
+	Index   int
+	Name    string
+	Err     error
+	Latency time.Duration
+}
+
+// WriteError is returned when writing data into some of cache sources failed
+type WriteError struct {
+	Results []WriteResult
+}
+
+// Error returns error message.
+func (e *WriteError) Error() string {
+	msgs := make([]string, len(e.Results))
+	for i, r := range e.Results {
+		msgs[i] = fmt.Sprintf("[%d:%s] %s", r.Index, r.Name, r.Err.Error())
+	}
+	return fmt.Sprintf("eurekache: failed to write into %d cache sources: %s", len(e.Results), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns errors of cache sources.
+func (e *WriteError) Unwrap() []error {
+	errs := make([]error, len(e.Results))
+	for i, r := range e.Results {
+		errs[i] = r.Err
+	}
+	return errs
+}
+
+// SetWriteStrategy sets strategy for writing data into cache sources
+func (e *Eurekache) SetWriteStrategy(s WriteStrategy) {
+	e.writeStrategy = s
+}
+
+// SetAsyncErrorHandler sets handler called when writing data on background failed
+func (e *Eurekache) SetAsyncErrorHandler(fn func(key string, err error)) {
+	e.asyncErrorHandler = fn
+}
+
+// write runs fn for each cache sources by the write strategy until write timeout,
+// and notifies the change of the key.
+func (e *Eurekache) write(key string, fn func(Cache) error) error {
+	caches := e.caches
+	if e.writeStrategy == WriteFirstThenAsync && len(caches) > 1 {
+		return e.writeFirstThenAsync(key, caches, fn)
+	}
+
+	var results []WriteResult
+	err := withTimeout(context.Background(), e.writeTimeout, func(ctx context.Context) {
+		defer e.notifyDelete(key)
+		results = writeSources(ctx, caches, 0, e.writeStrategy == WriteParallel, fn)
+	})
+	if err != nil {
+		return err
+	}
+	return newWriteError(results)
+}
+
+// writeFirstThenAsync writes data into the first cache source and writes into others on background
+func (e *Eurekache) writeFirstThenAsync(key string, caches []Cache, fn func(Cache) error) error {
+	var results []WriteResult
+	err := withTimeout(context.Background(), e.writeTimeout, func(ctx context.Context) {
+		results = writeSources(ctx, caches[:1], 0, false, fn)
+	})
+
+	e.asyncWriter.run(key, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), e.writeTimeout)
+		defer cancel()
+		defer e.notifyDelete(key)
+
+		asyncErr := newWriteError(writeSources(ctx, caches[1:], 1, true, fn))
+		if asyncErr != nil && e.asyncErrorHandler != nil {
+			e.asyncErrorHandler(key, asyncErr)
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+	return newWriteError(results)
+}
+
+// asyncWriter runs background writes, and the writes of the same key are run in order
+type asyncWriter struct {
+	mu sync.Mutex
+	// pending writes of the key, and the key exists while the writes are running
+	pending map[string][]func()
+}
+
+// run runs fn on background after the preceding writes of the key
+func (w *asyncWriter) run(key string, fn func()) {
+	w.mu.Lock()
+	if w.pending == nil {
+		w.pending = make(map[string][]func())
+	}
+	queue, running := w.pending[key]
+	w.pending[key] = append(queue, fn)
+	w.mu.Unlock()
+
+	if !running {
+		go w.drain(key)
+	}
+}
+
+// drain runs the pending writes of the key until the queue becomes empty
+func (w *asyncWriter) drain(key string) {
+	for {
+		w.mu.Lock()
+		queue := w.pending[key]
+		if len(queue) == 0 {
+			delete(w.pending, key)
+			w.mu.Unlock()
+			return
+		}
+		fn := queue[0]
+		w.pending[key] = queue[1:]
+		w.mu.Unlock()
+
+		fn()
+	}
+}
+
+// writeSources runs fn for each cache sources and returns the results.
+// index of results starts from offset.
+func writeSources(ctx context.Context, caches []Cache, offset int, parallel bool, fn func(Cache) error) []WriteResult {
+	results := make([]WriteResult, len(caches))
+	writeOne := func(i int) {
+		c := caches[i]
+		start := time.Now()
+		err := fn(c)
+		results[i] = WriteResult{
+			Index:   offset + i,
+			Name:    sourceName(c),
+			Err:     err,
+			Latency: time.Since(start),
+		}
+	}
+
+	if !parallel {
+		for i := range caches {
+			if ctx.Err() != nil {
+				return results[:i]
+			}
+			writeOne(i)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i := range caches {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			writeOne(i)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// newWriteError returns *WriteError when the results contain errors
+func newWriteError(results []WriteResult) error {
+	var failed []WriteResult
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+	return &WriteError{
+		Results: failed,
+	}
+}
@@ -0,0 +1,179 @@
+package eurekache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetWriteStrategy(t *testing.T) {
+	assert := assert.New(t)
+
+	e := New()
+	assert.Equal(WriteSequential, e.writeStrategy)
+
+	e.SetWriteStrategy(WriteParallel)
+	assert.Equal(WriteParallel, e.writeStrategy)
+}
+
+func TestWriteSequential(t *testing.T) {
+	assert := assert.New(t)
+
+	errSource := errors.New("source error")
+	c1 := &dummyWriteCache{}
+	c2 := &dummyWriteCache{err: errSource}
+	c3 := &dummyWriteCache{}
+
+	e := New()
+	e.SetCacheSources([]Cache{c1, c2, c3})
+
+	err := e.Set("key", "value")
+	assert.EqualError(err, "eurekache: failed to write into 1 cache sources: [1:*eurekache.dummyWriteCache] source error")
+	assert.True(errors.Is(err, errSource))
+
+	writeErr, ok := err.(*WriteError)
+	assert.True(ok)
+	assert.Len(writeErr.Results, 1)
+	assert.Equal(1, writeErr.Results[0].Index)
+
+	// other sources are written
+	assert.EqualValues(1, c1.count())
+	assert.EqualValues(1, c3.count())
+
+	// no error
+	e.SetCacheSources([]Cache{c1, c3})
+	assert.NoError(e.SetExpire("key", "value", 100))
+}
+
+func TestWriteParallel(t *testing.T) {
+	assert := assert.New(t)
+
+	c1 := &dummyWriteCache{sleep: 50 * time.Millisecond}
+	c2 := &dummyWriteCache{sleep: 50 * time.Millisecond}
+
+	e := New()
+	e.SetCacheSources([]Cache{c1, c2})
+	e.SetWriteStrategy(WriteParallel)
+
+	start := time.Now()
+	err := e.Set("key", "value")
+	assert.NoError(err)
+	assert.True(time.Since(start) < 90*time.Millisecond)
+	assert.EqualValues(1, c1.count())
+	assert.EqualValues(1, c2.count())
+
+	// timeout
+	e.SetWriteTimeout(10 * time.Millisecond)
+	err = e.Set("key", "value")
+	assert.Equal(ErrTimeout, err)
+}
+
+func TestWriteFirstThenAsync(t *testing.T) {
+	assert := assert.New(t)
+
+	errSource := errors.New("source error")
+	c1 := &dummyWriteCache{}
+	c2 := &dummyWriteCache{sleep: 50 * time.Millisecond, err: errSource}
+
+	e := New()
+	e.SetCacheSources([]Cache{c1, c2})
+	e.SetWriteStrategy(WriteFirstThenAsync)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var asyncErr error
+	e.SetAsyncErrorHandler(func(key string, err error) {
+		asyncErr = err
+		wg.Done()
+	})
+
+	start := time.Now()
+	err := e.Set("key", "value")
+	assert.NoError(err)
+	assert.True(time.Since(start) < 40*time.Millisecond)
+	assert.EqualValues(1, c1.count())
+
+	wg.Wait()
+	assert.EqualValues(1, c2.count())
+	assert.True(errors.Is(asyncErr, errSource))
+	assert.Equal(1, asyncErr.(*WriteError).Results[0].Index)
+}
+
+func TestWriteFirstThenAsyncOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	c1 := &dummyWriteCache{}
+	c2 := &dummyOrderCache{}
+
+	e := New()
+	e.SetCacheSources([]Cache{c1, c2})
+	e.SetWriteStrategy(WriteFirstThenAsync)
+
+	// the first write is slower than the second one
+	assert.NoError(e.Set("key", "slow"))
+	assert.NoError(e.Set("key", "fast"))
+	assert.NoError(e.Set("other", "value"))
+
+	assert.Eventually(func() bool {
+		return c2.value("other") == "value"
+	}, time.Second, time.Millisecond)
+	assert.Equal(nil, c2.value("key"), "the write of other key is not blocked")
+
+	assert.Eventually(func() bool {
+		return c2.value("key") == "fast"
+	}, time.Second, time.Millisecond)
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal("fast", c2.value("key"))
+}
+
+// dummyOrderCache keeps the written values, and writes "slow" slowly
+type dummyOrderCache struct {
+	dummyCache
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+func (d *dummyOrderCache) Set(k string, v interface{}) error {
+	if v == "slow" {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.values == nil {
+		d.values = make(map[string]interface{})
+	}
+	d.values[k] = v
+	return nil
+}
+
+func (d *dummyOrderCache) value(k string) interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.values[k]
+}
+
+type dummyWriteCache struct {
+	dummyCache
+	sleep  time.Duration
+	err    error
+	called int32
+}
+
+func (d *dummyWriteCache) Set(k string, v interface{}) error {
+	return d.SetExpire(k, v, 0)
+}
+
+func (d *dummyWriteCache) SetExpire(k string, v interface{}, ttl int64) error {
+	time.Sleep(d.sleep)
+	atomic.AddInt32(&d.called, 1)
+	return d.err
+}
+
+func (d *dummyWriteCache) count() int32 {
+	return atomic.LoadInt32(&d.called)
+}
@@ -0,0 +1,370 @@
+package eurekache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const (
+	defaultWriteBehindBatchSize = 100
+)
+
+var (
+	// ErrQueueFull is returned when the write-behind queue is full on OverflowDrop policy
+	ErrQueueFull = errors.New("eurekache: write-behind queue is full")
+	// ErrQueueClosed is returned when writing into closed write-behind queue
+	ErrQueueClosed = errors.New("eurekache: write-behind queue is closed")
+)
+
+// OverflowPolicy is a policy when the write-behind queue is full
+type OverflowPolicy int
+
+// overflow policies
+const (
+	// OverflowBlock waits until the queue has space
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop drops the new data and returns ErrQueueFull
+	OverflowDrop
+	// OverflowDropOldest drops the oldest data in the queue and reports it to the error handler with ErrQueueFull
+	OverflowDropOldest
+)
+
+// WriteEntry is data written into cache source
+type WriteEntry struct {
+	Key   string
+	Value interface{}
+	TTL   int64
+	Tags  []string
+
+	// use default TTL of the cache source instead of TTL
+	UseDefaultTTL bool
+}
+
+// BatchWriter is interface for cache source writing multiple data at once
+type BatchWriter interface {
+	SetMulti([]WriteEntry) error
+}
+
+// WriteBehindCache is a cache source wrapping slow cache source,
+// and writes data into the source on background.
+// Repeated writes for the same key in the queue are coalesced into the latest one.
+type WriteBehindCache struct {
+	cache     Cache
+	maxSize   int
+	batchSize int
+	policy    OverflowPolicy
+	onError   func(string, error)
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	pending  map[string]WriteEntry
+	order    []string
+	inflight map[string]WriteEntry
+	closed   bool
+	done     chan struct{}
+}
+
+// NewWriteBehindCache returns initialized WriteBehindCache and starts writing on background.
+// size limits the number of keys in the queue, and zero or negative size means unbounded.
+func NewWriteBehindCache(cache Cache, size int) *WriteBehindCache {
+	c := &WriteBehindCache{
+		cache:     cache,
+		maxSize:   size,
+		batchSize: defaultWriteBehindBatchSize,
+		pending:   make(map[string]WriteEntry),
+		inflight:  make(map[string]WriteEntry),
+		done:      make(chan struct{}),
+	}
+	c.cond = sync.NewCond(&c.mu)
+
+	go c.run()
+	return c
+}
+
+// SetBatchSize sets maximum number of data written at once
+func (c *WriteBehindCache) SetBatchSize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.batchSize = size
+}
+
+// SetOverflowPolicy sets policy when the queue is full
+func (c *WriteBehindCache) SetOverflowPolicy(p OverflowPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.policy = p
+}
+
+// SetErrorHandler sets handler called when writing data on background failed
+func (c *WriteBehindCache) SetErrorHandler(fn func(key string, err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onError = fn
+}
+
+// Get searches data in the queue at first, and searches the cache source.
+func (c *WriteBehindCache) Get(key string, data interface{}) bool {
+	if entry, ok := c.queued(key); ok {
+		return entry.Value != nil && CopyValue(data, entry.Value)
+	}
+	return c.cache.Get(key, data)
+}
+
+// GetInterface searches data in the queue at first, and searches the cache source.
+func (c *WriteBehindCache) GetInterface(key string) (interface{}, bool) {
+	if entry, ok := c.queued(key); ok {
+		return entry.Value, entry.Value != nil
+	}
+	return c.cache.GetInterface(key)
+}
+
+// GetGobBytes flushes the data of the key and searches the cache source.
+func (c *WriteBehindCache) GetGobBytes(key string) ([]byte, bool) {
+	if _, ok := c.queued(key); ok {
+		c.Flush()
+	}
+	return c.cache.GetGobBytes(key)
+}
+
+// Set puts data into the queue.
+func (c *WriteBehindCache) Set(key string, data interface{}) error {
+	return c.enqueue(WriteEntry{
+		Key:           key,
+		Value:         data,
+		UseDefaultTTL: true,
+	})
+}
+
+// SetExpire puts data with TTL into the queue.
+func (c *WriteBehindCache) SetExpire(key string, data interface{}, ttl int64) error {
+	return c.enqueue(WriteEntry{
+		Key:   key,
+		Value: data,
+		TTL:   ttl,
+	})
+}
+
+// SetWithTags puts data with TTL and tags into the queue.
+func (c *WriteBehindCache) SetWithTags(key string, data interface{}, ttl int64, tags ...string) error {
+	return c.enqueue(WriteEntry{
+		Key:   key,
+		Value: data,
+		TTL:   ttl,
+		Tags:  tags,
+	})
+}
+
+// InvalidateTag flushes the queue and invalidates the tag on the cache source.
+func (c *WriteBehindCache) InvalidateTag(tag string) error {
+	c.Flush()
+	if tc, ok := c.cache.(TagCache); ok {
+		return tc.InvalidateTag(tag)
+	}
+	return nil
+}
+
+// Clear discards the queue and deletes all of cached data from the cache source.
+func (c *WriteBehindCache) Clear() error {
+	c.mu.Lock()
+	c.pending = make(map[string]WriteEntry)
+	c.order = nil
+	c.cond.Broadcast()
+	c.mu.Unlock()
+
+	c.Flush()
+	return c.cache.Clear()
+}
+
+// Len returns the number of keys in the queue.
+func (c *WriteBehindCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// Ping checks the cache source.
+func (c *WriteBehindCache) Ping(ctx context.Context) error {
+	if p, ok := c.cache.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// Flush waits until all of data in the queue is written.
+func (c *WriteBehindCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for (len(c.pending) > 0 || len(c.inflight) > 0) && !c.isStopped() {
+		c.cond.Wait()
+	}
+}
+
+// Close writes all of data in the queue and stops writing on background.
+func (c *WriteBehindCache) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.cond.Broadcast()
+	c.mu.Unlock()
+
+	<-c.done
+	return nil
+}
+
+// enqueue puts the entry into the queue, and reports the dropped entry to the error handler
+func (c *WriteBehindCache) enqueue(entry WriteEntry) error {
+	if entry.Key == "" {
+		return nil
+	}
+
+	dropped, onError, err := c.push(entry)
+	if dropped != "" && onError != nil {
+		onError(dropped, ErrQueueFull)
+	}
+	return err
+}
+
+// push puts the entry into the queue and returns the key dropped by OverflowDropOldest
+func (c *WriteBehindCache) push(entry WriteEntry) (dropped string, onError func(string, error), err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return "", nil, ErrQueueClosed
+	}
+
+	// coalesce
+	if _, ok := c.pending[entry.Key]; ok {
+		c.pending[entry.Key] = entry
+		return "", nil, nil
+	}
+
+	for c.maxSize > 0 && len(c.pending) >= c.maxSize {
+		switch c.policy {
+		case OverflowDrop:
+			return "", nil, ErrQueueFull
+		case OverflowDropOldest:
+			dropped = c.order[0]
+			c.order = c.order[1:]
+			delete(c.pending, dropped)
+		default:
+			c.cond.Wait()
+			if c.closed {
+				return "", nil, ErrQueueClosed
+			}
+			if _, ok := c.pending[entry.Key]; ok {
+				c.pending[entry.Key] = entry
+				return "", nil, nil
+			}
+		}
+	}
+
+	c.pending[entry.Key] = entry
+	c.order = append(c.order, entry.Key)
+	c.cond.Broadcast()
+	return dropped, c.onError, nil
+}
+
+// queued returns the entry in the queue or being written
+func (c *WriteBehindCache) queued(key string) (WriteEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.pending[key]; ok {
+		return entry, true
+	}
+	entry, ok := c.inflight[key]
+	return entry, ok
+}
+
+// run writes data in the queue until closed
+func (c *WriteBehindCache) run() {
+	defer close(c.done)
+	for {
+		batch, onError, ok := c.dequeue()
+		if !ok {
+			return
+		}
+
+		err := c.writeBatch(batch)
+		if err != nil && onError != nil {
+			for _, entry := range batch {
+				onError(entry.Key, err)
+			}
+		}
+
+		c.mu.Lock()
+		c.inflight = make(map[string]WriteEntry)
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	}
+}
+
+// dequeue waits for data and takes the entries from the queue
+func (c *WriteBehindCache) dequeue() ([]WriteEntry, func(string, error), bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.order) == 0 {
+		if c.closed {
+			return nil, nil, false
+		}
+		c.cond.Wait()
+	}
+
+	size := c.batchSize
+	if size < 1 || size > len(c.order) {
+		size = len(c.order)
+	}
+
+	batch := make([]WriteEntry, size)
+	for i, key := range c.order[:size] {
+		batch[i] = c.pending[key]
+		c.inflight[key] = batch[i]
+		delete(c.pending, key)
+	}
+	c.order = c.order[size:]
+	c.cond.Broadcast()
+	return batch, c.onError, true
+}
+
+// writeBatch writes the entries into the cache source
+func (c *WriteBehindCache) writeBatch(batch []WriteEntry) error {
+	if bw, ok := c.cache.(BatchWriter); ok {
+		return bw.SetMulti(batch)
+	}
+
+	var firstErr error
+	for _, entry := range batch {
+		err := writeEntry(c.cache, entry)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// isStopped checks if the background writer is stopped or not
+func (c *WriteBehindCache) isStopped() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeEntry writes the entry into the cache source
+func writeEntry(c Cache, entry WriteEntry) error {
+	switch {
+	case len(entry.Tags) != 0:
+		if tc, ok := c.(TagCache); ok {
+			return tc.SetWithTags(entry.Key, entry.Value, entry.TTL, entry.Tags...)
+		}
+		return c.SetExpire(entry.Key, entry.Value, entry.TTL)
+	case entry.UseDefaultTTL:
+		return c.Set(entry.Key, entry.Value)
+	default:
+		return c.SetExpire(entry.Key, entry.Value, entry.TTL)
+	}
+}
@@ -0,0 +1,275 @@
+package eurekache
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWriteBehindCache(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewWriteBehindCache(&dummyCache{}, 10)
+	defer c.Close()
+	assert.Equal(10, c.maxSize)
+	assert.Equal(defaultWriteBehindBatchSize, c.batchSize)
+	assert.Equal(OverflowBlock, c.policy)
+
+	c.SetBatchSize(5)
+	assert.Equal(5, c.batchSize)
+	c.SetOverflowPolicy(OverflowDrop)
+	assert.Equal(OverflowDrop, c.policy)
+}
+
+func TestWriteBehindCacheSet(t *testing.T) {
+	assert := assert.New(t)
+
+	src := newDummyBatchCache()
+	c := NewWriteBehindCache(src, 10)
+	defer c.Close()
+
+	src.block()
+	assert.NoError(c.Set("key1", "value1"))
+	assert.NoError(c.SetExpire("key2", "value2", 100))
+	assert.NoError(c.SetWithTags("key3", "value3", 100, "tag"))
+
+	// read from the queue
+	var v string
+	assert.True(c.Get("key2", &v))
+	assert.Equal("value2", v)
+	iv, ok := c.GetInterface("key3")
+	assert.True(ok)
+	assert.Equal("value3", iv)
+
+	src.unblock()
+	c.Flush()
+	assert.Equal(0, c.Len())
+
+	entries := src.written()
+	assert.Len(entries, 3)
+	assert.True(entries["key1"].UseDefaultTTL)
+	assert.EqualValues(100, entries["key2"].TTL)
+	assert.Equal([]string{"tag"}, entries["key3"].Tags)
+}
+
+func TestWriteBehindCacheCoalesce(t *testing.T) {
+	assert := assert.New(t)
+
+	src := newDummyBatchCache()
+	c := NewWriteBehindCache(src, 10)
+	defer c.Close()
+
+	// the first write is taken by the writer and blocked
+	src.block()
+	c.Set("first", "value")
+	assert.Eventually(func() bool { return c.Len() == 0 }, time.Second, time.Millisecond)
+
+	c.Set("key", "value1")
+	c.Set("key", "value2")
+	c.Set("key", nil)
+	c.Set("key", "value3")
+	assert.Equal(1, c.Len())
+
+	src.unblock()
+	c.Flush()
+	assert.Equal(2, src.count())
+	assert.Equal("value3", src.written()["key"].Value)
+}
+
+func TestWriteBehindCacheOverflow(t *testing.T) {
+	assert := assert.New(t)
+
+	src := newDummyBatchCache()
+	c := NewWriteBehindCache(src, 2)
+	defer c.Close()
+
+	src.block()
+	c.Set("first", "value")
+	assert.Eventually(func() bool { return c.Len() == 0 }, time.Second, time.Millisecond)
+	c.Set("key1", "value")
+	c.Set("key2", "value")
+
+	// drop
+	c.SetOverflowPolicy(OverflowDrop)
+	assert.Equal(ErrQueueFull, c.Set("key3", "value"))
+	assert.NoError(c.Set("key2", "value2"), "coalesced write does not overflow")
+
+	// drop oldest
+	var dropped []string
+	c.SetErrorHandler(func(key string, err error) {
+		assert.Equal(ErrQueueFull, err)
+		dropped = append(dropped, key)
+	})
+	c.SetOverflowPolicy(OverflowDropOldest)
+	assert.NoError(c.Set("key3", "value"))
+	_, ok := c.GetInterface("key1")
+	assert.False(ok)
+	assert.Equal([]string{"key1"}, dropped)
+	c.SetErrorHandler(nil)
+
+	// block
+	c.SetOverflowPolicy(OverflowBlock)
+	done := make(chan error)
+	go func() {
+		done <- c.Set("key4", "value")
+	}()
+	select {
+	case <-done:
+		assert.Fail("Set must be blocked")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	src.unblock()
+	assert.NoError(<-done)
+	c.Flush()
+
+	entries := src.written()
+	assert.NotContains(entries, "key1")
+	assert.Contains(entries, "key2")
+	assert.Contains(entries, "key3")
+	assert.Contains(entries, "key4")
+}
+
+func TestWriteBehindCacheUnbounded(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, policy := range []OverflowPolicy{OverflowBlock, OverflowDrop, OverflowDropOldest} {
+		src := newDummyBatchCache()
+		c := NewWriteBehindCache(src, 0)
+		c.SetOverflowPolicy(policy)
+
+		src.block()
+		for i := 0; i < 10; i++ {
+			assert.NoError(c.Set("key"+strconv.Itoa(i), i))
+		}
+		src.unblock()
+		c.Close()
+		assert.Len(src.written(), 10)
+	}
+}
+
+func TestWriteBehindCacheBatch(t *testing.T) {
+	assert := assert.New(t)
+
+	src := newDummyBatchCache()
+	c := NewWriteBehindCache(src, 10)
+	c.SetBatchSize(2)
+
+	src.block()
+	c.Set("first", "value")
+	assert.Eventually(func() bool { return c.Len() == 0 }, time.Second, time.Millisecond)
+	for _, key := range []string{"key1", "key2", "key3", "key4", "key5"} {
+		c.Set(key, "value")
+	}
+	src.unblock()
+
+	// Close drains the queue
+	assert.NoError(c.Close())
+	assert.Equal(6, src.count())
+	assert.Equal([]int{1, 2, 2, 1}, src.batchSizes())
+	assert.Equal(ErrQueueClosed, c.Set("key", "value"))
+}
+
+func TestWriteBehindCacheError(t *testing.T) {
+	assert := assert.New(t)
+
+	errSource := errors.New("source error")
+	src := &dummyWriteCache{err: errSource}
+	c := NewWriteBehindCache(src, 10)
+
+	var mu sync.Mutex
+	var keys []string
+	c.SetErrorHandler(func(key string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(errSource, err)
+		keys = append(keys, key)
+	})
+
+	assert.NoError(c.Set("key", "value"))
+	c.Close()
+
+	assert.Equal([]string{"key"}, keys)
+	assert.EqualValues(1, src.count())
+}
+
+func TestWriteBehindCacheWithEurekache(t *testing.T) {
+	assert := assert.New(t)
+
+	slow := newDummyBatchCache()
+	c := NewWriteBehindCache(slow, 10)
+	defer c.Close()
+
+	e := New()
+	e.SetCacheSources([]Cache{c})
+
+	slow.block()
+	assert.NoError(e.Set("key", "value"))
+
+	var v string
+	assert.True(e.Get("key", &v))
+	assert.Equal("value", v)
+
+	slow.unblock()
+	c.Flush()
+	assert.Equal("value", slow.written()["key"].Value)
+}
+
+type dummyBatchCache struct {
+	dummyCache
+	mu      sync.Mutex
+	gate    sync.RWMutex
+	entries map[string]WriteEntry
+	batches []int
+	total   int
+}
+
+func newDummyBatchCache() *dummyBatchCache {
+	return &dummyBatchCache{
+		entries: make(map[string]WriteEntry),
+	}
+}
+
+func (d *dummyBatchCache) SetMulti(entries []WriteEntry) error {
+	d.gate.RLock()
+	defer d.gate.RUnlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, e := range entries {
+		d.entries[e.Key] = e
+	}
+	d.batches = append(d.batches, len(entries))
+	d.total += len(entries)
+	return nil
+}
+
+func (d *dummyBatchCache) block() {
+	d.gate.Lock()
+}
+
+func (d *dummyBatchCache) unblock() {
+	d.gate.Unlock()
+}
+
+func (d *dummyBatchCache) written() map[string]WriteEntry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.entries
+}
+
+func (d *dummyBatchCache) batchSizes() []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.batches
+}
+
+func (d *dummyBatchCache) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.total
+}